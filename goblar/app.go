@@ -1,9 +1,18 @@
 package goblar
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 
+	blarhttp "github.com/kamil5b/go-blar/internal/http"
 	"github.com/kamil5b/go-blar/internal/meta"
 	"gorm.io/gorm"
 )
@@ -12,9 +21,12 @@ import (
 // It holds minimal public state and hides internal implementation details.
 type App struct {
 	db       *gorm.DB
-	router   http.Handler
+	router   *blarhttp.Router
 	cfg      *config
-	registry map[string]any
+	registry map[string]*meta.EntityMeta
+
+	mu     sync.Mutex
+	server *http.Server
 }
 
 // New creates a new App with the given options.
@@ -25,19 +37,26 @@ func New(opts ...Option) *App {
 	app := &App{
 		db:       cfg.db,
 		cfg:      cfg,
-		registry: make(map[string]any),
+		registry: make(map[string]*meta.EntityMeta),
 	}
 
 	return app
 }
 
 // Register registers one or more model structs with the app.
-// Models must be valid GORM entities.
+// Models must be valid GORM entities, registered before Handler, Mount, Start
+// or Run builds the router; later calls return an error.
 func (a *App) Register(models ...any) error {
 	if a.db == nil {
 		return fmt.Errorf("database not configured: use WithDB option")
 	}
 
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.router != nil {
+		return errors.New("cannot register models after the router is built")
+	}
+
 	// Parse and validate each model
 	for _, model := range models {
 		entityMeta, err := meta.Parse(model)
@@ -54,24 +73,164 @@ func (a *App) Register(models ...any) error {
 		a.registry[entityMeta.Name] = entityMeta
 	}
 
+	// Resolve fk: tags against the registered entities in a stable order, so
+	// requests do not look them up
+	entities := slices.SortedFunc(maps.Values(a.registry), func(x, y *meta.EntityMeta) int {
+		return strings.Compare(x.Name, y.Name)
+	})
+	meta.ResolveForeignKeys(entities)
+
 	return nil
 }
 
 // Start starts the HTTP server and serves the auto-generated routes.
+// It blocks until the server fails or is stopped with Shutdown, in which case it returns nil.
 func (a *App) Start() error {
+	err := a.newServer().ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Run starts the HTTP server and blocks until ctx is cancelled or the server fails.
+// On cancellation it shuts down gracefully, waiting up to the shutdown timeout
+// for in-flight requests to finish.
+func (a *App) Run(ctx context.Context) error {
+	server := a.newServer()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.shutdownTimeout)
+		defer cancel()
+		return a.Shutdown(shutdownCtx)
+	}
+}
+
+// Shutdown gracefully stops the server started by Start or Run.
+// It stops accepting connections and waits for in-flight requests until ctx expires.
+// With WithCloseDBOnShutdown, the database connection pool is closed afterwards.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	server := a.server
+	a.mu.Unlock()
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+
+	if a.cfg.closeDB && a.db != nil {
+		sqlDB, dbErr := a.db.DB()
+		if dbErr == nil {
+			dbErr = sqlDB.Close()
+		}
+		if dbErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close database: %w", dbErr))
+		}
+	}
+
+	return err
+}
+
+// newServer creates the HTTP server from the configured address and timeouts.
+func (a *App) newServer() *http.Server {
 	if a.cfg.addr == "" {
 		a.cfg.addr = ":8080"
 	}
 
-	// Build HTTP router (stubbed for now - will be implemented in internal/http)
-	// TODO: Build router with registered models
-	// TODO: Apply middleware
-	// TODO: Register routes for all entities
-
 	server := &http.Server{
-		Addr:    a.cfg.addr,
-		Handler: a.router,
+		Addr:              a.cfg.addr,
+		Handler:           a.Handler(),
+		ReadTimeout:       a.cfg.readTimeout,
+		ReadHeaderTimeout: a.cfg.readHeaderTimeout,
+		WriteTimeout:      a.cfg.writeTimeout,
+		IdleTimeout:       a.cfg.idleTimeout,
+	}
+
+	a.mu.Lock()
+	a.server = server
+	a.mu.Unlock()
+
+	return server
+}
+
+// Handler returns the HTTP handler serving the routes for all registered entities.
+// Use it to run go-blar inside an existing server or with httptest.NewServer.
+// The router is built on first use, after which Register fails.
+func (a *App) Handler() http.Handler {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.router == nil {
+		a.router = a.buildRouter()
+	}
+	return a.router
+}
+
+// Mount returns the app's handler for serving under the given path prefix,
+// e.g. mux.Handle("/api/v1/", app.Mount("/api/v1")).
+// The prefix is stripped before routing, so /api/v1/product serves /product.
+// Routers that strip mount prefixes themselves, such as chi's Mount, should use Handler instead.
+func (a *App) Mount(prefix string) http.Handler {
+	return http.StripPrefix(strings.TrimSuffix(prefix, "/"), a.Handler())
+}
+
+// buildRouter builds the HTTP router for all registered entities.
+// Middleware is applied first, in the order it was configured.
+func (a *App) buildRouter() *blarhttp.Router {
+	router := blarhttp.New()
+	for _, m := range a.cfg.middleware {
+		router.AddMiddleware(m)
 	}
+	router.ApplyMiddleware()
 
-	return server.ListenAndServe()
+	handlers := blarhttp.NewHandlers(a.db, blarhttp.Options{
+		DefaultPageSize:  a.cfg.defaultPageSize,
+		MaxPageSize:      a.cfg.maxPageSize,
+		Pagination:       a.cfg.pagination,
+		CursorPagination: modelTypes(a.cfg.cursorModels),
+		CursorSecret:     a.cursorSecret(),
+		MaxIncludeDepth:  a.cfg.maxIncludeDepth,
+		ReadOnly:         a.cfg.readOnly,
+		Hooks:            &a.cfg.hooks,
+	})
+	for _, entityMeta := range a.registry {
+		blarhttp.RegisterEntityRoutes(router, entityMeta, handlers)
+	}
+
+	return router
+}
+
+// cursorSecret returns the configured cursor signing key, or a random one.
+func (a *App) cursorSecret() []byte {
+	if len(a.cfg.cursorSecret) > 0 {
+		return a.cfg.cursorSecret
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
+
+// modelTypes returns the set of struct types of the given models.
+func modelTypes(models []any) map[reflect.Type]bool {
+	types := make(map[reflect.Type]bool, len(models))
+	for _, model := range models {
+		t := reflect.TypeOf(model)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		types[t] = true
+	}
+	return types
 }
@@ -1,7 +1,14 @@
 package goblar
 
 import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -127,3 +134,275 @@ func TestRegisterMultiple(t *testing.T) {
 		t.Fatalf("expected 2 entities in registry, got %d", len(app.registry))
 	}
 }
+
+func TestHandlerServesRegisteredEntities(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(WithDB(db))
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := app.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/test-entity", strings.NewReader(`{"Name":"widget"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/test-entity/1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var got TestEntity
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "widget" {
+		t.Fatalf("expected name widget, got %s", got.Name)
+	}
+}
+
+func TestHandlerAppliesMiddlewareInOrder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	app := New(WithDB(db), WithMiddleware(record("first")), WithMiddleware(record("second")))
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/test-entity", nil)
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Fatalf("expected middleware order [first second], got %v", calls)
+	}
+}
+
+func TestHandlerWithTestServer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(WithDB(db))
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/test-entity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestMount(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(WithDB(db))
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/", app.Mount("/api/v1/"))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/test-entity", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 under prefix, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for host route, got %d", w.Code)
+	}
+}
+
+func TestRegisterAfterHandler(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Order struct {
+		ID    uint
+		Total float64
+	}
+
+	app := New(WithDB(db))
+	handler := app.Handler()
+
+	if err := app.Register(&Order{}); err == nil {
+		t.Fatal("expected registering after Handler to fail")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/order", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+// freeAddr returns a local address with a port that is currently free.
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// waitForServer polls addr until it accepts connections.
+func waitForServer(t *testing.T, addr string) {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s did not start", addr)
+}
+
+func TestRunStopsOnContextCancel(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := freeAddr(t)
+	app := New(WithDB(db), WithAddress(addr))
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+	waitForServer(t, addr)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return after cancel")
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	slow := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(entered)
+			<-release
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	addr := freeAddr(t)
+	app := New(WithDB(db), WithAddress(addr), WithMiddleware(slow), WithCloseDBOnShutdown())
+	if err := app.Register(&TestEntity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- app.Start()
+	}()
+	waitForServer(t, addr)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/test-entity")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-entered
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- app.Shutdown(context.Background())
+	}()
+
+	close(release)
+
+	if code := <-status; code != http.StatusOK {
+		t.Fatalf("expected in-flight request to complete with 200, got %d", code)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("expected no shutdown error, got %v", err)
+	}
+	if err := <-startErr; err != nil {
+		t.Fatalf("expected Start to return nil after shutdown, got %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Ping(); err == nil {
+		t.Fatal("expected database to be closed after shutdown")
+	}
+}
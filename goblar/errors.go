@@ -0,0 +1,41 @@
+package goblar
+
+import (
+	"net/http"
+
+	blarhttp "github.com/kamil5b/go-blar/internal/http"
+)
+
+// Error is an error with an HTTP status. Handlers write it to clients as an
+// RFC 7807 application/problem+json response; hooks can return one to choose
+// the status code and message:
+//
+//	return &goblar.Error{Status: http.StatusUnprocessableEntity, Detail: "order is closed"}
+//
+// Any other error returned by a hook or the database becomes a 500 whose
+// details are logged but not sent to the client.
+type Error = blarhttp.Error
+
+// FieldError describes an invalid field, listed under "errors" in a problem response.
+type FieldError = blarhttp.FieldError
+
+// Errors hooks can return, or wrap to add detail, to answer with a 4xx status:
+//
+//	if p.Price < 0 {
+//		return fmt.Errorf("%w: price cannot be negative", goblar.ErrValidation)
+//	}
+//
+// The response detail is then "validation failed: price cannot be negative".
+// Handlers find them with errors.As, and callers can test for them with errors.Is.
+var (
+	ErrBadRequest = NewHTTPError(http.StatusBadRequest, "bad request")
+	ErrForbidden  = NewHTTPError(http.StatusForbidden, "forbidden")
+	ErrNotFound   = NewHTTPError(http.StatusNotFound, "not found")
+	ErrConflict   = NewHTTPError(http.StatusConflict, "conflict")
+	ErrValidation = NewHTTPError(http.StatusUnprocessableEntity, "validation failed")
+)
+
+// NewHTTPError returns an *Error with the given status code and detail message.
+func NewHTTPError(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
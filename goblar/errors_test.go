@@ -0,0 +1,101 @@
+package goblar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Ticket is an entity whose BeforeCreate hook fails depending on its name.
+type Ticket struct {
+	ID   uint
+	Name string
+}
+
+func (t *Ticket) BeforeCreate(ctx context.Context, tx *gorm.DB) error {
+	switch t.Name {
+	case "invalid":
+		return fmt.Errorf("%w: name is reserved", ErrValidation)
+	case "forbidden":
+		return ErrForbidden
+	case "duplicate":
+		return NewHTTPError(http.StatusConflict, "ticket already exists")
+	case "broken":
+		return errors.New("disk on fire")
+	}
+	return nil
+}
+
+func TestNewHTTPError(t *testing.T) {
+	err := NewHTTPError(http.StatusTeapot, "short and stout")
+	if err.Status != http.StatusTeapot || err.Error() != "short and stout" {
+		t.Fatalf("unexpected error %+v", err)
+	}
+
+	wrapped := fmt.Errorf("%w: name is reserved", ErrValidation)
+	if !errors.Is(wrapped, ErrValidation) || errors.Is(wrapped, ErrConflict) {
+		t.Fatal("expected errors.Is to match the wrapped sentinel only")
+	}
+	var e *Error
+	if !errors.As(wrapped, &e) || e.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected errors.As to find a 422 error, got %+v", e)
+	}
+}
+
+func TestHookErrorStatus(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(WithDB(db))
+	if err := app.Register(&Ticket{}); err != nil {
+		t.Fatal(err)
+	}
+	router := app.Handler()
+
+	tests := []struct {
+		name   string
+		status int
+		detail string
+	}{
+		{"invalid", http.StatusUnprocessableEntity, "validation failed: name is reserved"},
+		{"forbidden", http.StatusForbidden, "forbidden"},
+		{"duplicate", http.StatusConflict, "ticket already exists"},
+		{"broken", http.StatusInternalServerError, "an internal error occurred"},
+		{"fine", http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/ticket", strings.NewReader(`{"Name":"`+tt.name+`"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if tt.detail == "" {
+			continue
+		}
+
+		var problem struct {
+			Status int    `json:"status"`
+			Detail string `json:"detail"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		if problem.Status != tt.status || problem.Detail != tt.detail {
+			t.Errorf("%s: unexpected problem %+v", tt.name, problem)
+		}
+	}
+}
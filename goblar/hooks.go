@@ -2,36 +2,116 @@ package goblar
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
+	"github.com/kamil5b/go-blar/internal/hooks"
 	"gorm.io/gorm"
 )
 
+// The hook interfaces are defined in internal/hooks so the HTTP layer can
+// invoke them without importing this package. They are re-exported here as
+// aliases, so implementing either one satisfies both.
+
 // BeforeCreate is called before an entity is created.
-type BeforeCreate interface {
-	BeforeCreate(ctx context.Context, tx *gorm.DB) error
-}
+type BeforeCreate = hooks.BeforeCreate
 
 // AfterCreate is called after an entity is created.
-type AfterCreate interface {
-	AfterCreate(ctx context.Context, tx *gorm.DB) error
-}
+type AfterCreate = hooks.AfterCreate
 
 // BeforeUpdate is called before an entity is updated.
-type BeforeUpdate interface {
-	BeforeUpdate(ctx context.Context, tx *gorm.DB) error
-}
+type BeforeUpdate = hooks.BeforeUpdate
 
 // AfterUpdate is called after an entity is updated.
-type AfterUpdate interface {
-	AfterUpdate(ctx context.Context, tx *gorm.DB) error
-}
+type AfterUpdate = hooks.AfterUpdate
 
 // BeforeDelete is called before an entity is deleted.
-type BeforeDelete interface {
-	BeforeDelete(ctx context.Context, tx *gorm.DB) error
-}
+type BeforeDelete = hooks.BeforeDelete
 
 // AfterDelete is called after an entity is deleted.
-type AfterDelete interface {
-	AfterDelete(ctx context.Context, tx *gorm.DB) error
+type AfterDelete = hooks.AfterDelete
+
+// BeforeList is called on a zero entity before a list query and returns the
+// query to run, e.g. scoped to the current tenant or owner.
+type BeforeList = hooks.BeforeList
+
+// AfterList is called on each entity of a list page after it is loaded.
+type AfterList = hooks.AfterList
+
+// BeforeGet is called on a zero entity before a single entity is fetched and
+// returns the query to run, e.g. scoped to the current tenant or owner.
+type BeforeGet = hooks.BeforeGet
+
+// AfterGet is called after a single entity is fetched.
+type AfterGet = hooks.AfterGet
+
+// HookEvent names a lifecycle event for WithHook and HookFor.
+type HookEvent = hooks.Event
+
+const (
+	OnBeforeCreate = hooks.OnBeforeCreate
+	OnAfterCreate  = hooks.OnAfterCreate
+	OnBeforeUpdate = hooks.OnBeforeUpdate
+	OnAfterUpdate  = hooks.OnAfterUpdate
+	OnBeforeDelete = hooks.OnBeforeDelete
+	OnAfterDelete  = hooks.OnAfterDelete
+	OnAfterGet     = hooks.OnAfterGet
+	OnAfterList    = hooks.OnAfterList // called for each entity of a list page
+)
+
+// HookFunc is a hook registered with WithHook. entity is a pointer to the entity struct.
+type HookFunc = hooks.Func
+
+// WithHook registers a hook for event on all entities, e.g. for auditing,
+// without the models implementing hook methods:
+//
+//	goblar.WithHook(goblar.OnAfterCreate, func(ctx context.Context, entity any, tx *gorm.DB) error {
+//		return tx.Create(&AuditLog{Action: "create", Entity: fmt.Sprintf("%T", entity)}).Error
+//	})
+//
+// Before hooks run global hooks first, then HookFor hooks, then the entity's
+// hook method; After hooks run in the reverse order. Hooks registered for the
+// same event and entity run in registration order, and the first error stops
+// the operation.
+func WithHook(event HookEvent, fn HookFunc) Option {
+	return func(c *config) {
+		c.hooks.Add(event, nil, fn)
+	}
+}
+
+// HookFor registers a hook for event on entities of type T only:
+//
+//	goblar.HookFor(goblar.OnBeforeCreate, func(ctx context.Context, p *Product, tx *gorm.DB) error {
+//		p.Slug = slugify(p.Name)
+//		return nil
+//	})
+//
+// T is the entity struct type itself, not a pointer to it; HookFor panics
+// otherwise, since such a hook would never run. See WithHook for the order hooks
+// run in.
+func HookFor[T any](event HookEvent, fn func(ctx context.Context, entity *T, tx *gorm.DB) error) Option {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("goblar: HookFor type argument must be an entity struct type, got %s", t))
+	}
+
+	return func(c *config) {
+		c.hooks.Add(event, t, func(ctx context.Context, entity any, tx *gorm.DB) error {
+			return fn(ctx, entity.(*T), tx)
+		})
+	}
+}
+
+// Transformer converts a field value written by a client before it is saved,
+// e.g. hashing a password. Fields select one with go-blar:"transform:<name>".
+type Transformer = hooks.Transformer
+
+// RegisterTransformer registers a transformer under name, replacing any previous one.
+//
+//	goblar.RegisterTransformer("hash", func(ctx context.Context, value any) (any, error) {
+//		hash, err := bcrypt.GenerateFromPassword([]byte(value.(string)), bcrypt.DefaultCost)
+//		return string(hash), err
+//	})
+func RegisterTransformer(name string, fn Transformer) {
+	hooks.RegisterTransformer(name, fn)
 }
@@ -0,0 +1,98 @@
+package goblar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Note is an entity without hook methods.
+type Note struct {
+	ID   uint
+	Text string
+}
+
+func TestWithHookAndHookFor(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	app := New(
+		WithDB(db),
+		WithHook(OnBeforeCreate, func(ctx context.Context, entity any, tx *gorm.DB) error {
+			record("global")
+			return nil
+		}),
+		HookFor(OnBeforeCreate, func(ctx context.Context, n *Note, tx *gorm.DB) error {
+			record("note")
+			if n.Text == "" {
+				return errors.New("text is required")
+			}
+			n.Text = strings.ToUpper(n.Text)
+			return nil
+		}),
+		HookFor(OnBeforeCreate, func(ctx context.Context, e *TestEntity, tx *gorm.DB) error {
+			record("test entity")
+			return nil
+		}),
+	)
+	if err := app.Register(&Note{}); err != nil {
+		t.Fatal(err)
+	}
+	router := app.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/note", strings.NewReader(`{"Text":"hi"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if want := []string{"global", "note"}; !slices.Equal(calls, want) {
+		t.Fatalf("expected hooks %v, got %v", want, calls)
+	}
+
+	var note Note
+	if err := db.First(&note).Error; err != nil {
+		t.Fatal(err)
+	}
+	if note.Text != "HI" {
+		t.Fatalf("expected typed hook to modify the note, got %q", note.Text)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/note", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestHookForRejectsPointerTypes(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "*goblar.Note") {
+			t.Fatalf("expected HookFor to panic for *Note, got %v", r)
+		}
+	}()
+
+	HookFor(OnBeforeCreate, func(ctx context.Context, n **Note, tx *gorm.DB) error {
+		return nil
+	})
+}
@@ -2,7 +2,10 @@ package goblar
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/kamil5b/go-blar/internal/hooks"
+	blarhttp "github.com/kamil5b/go-blar/internal/http"
 	"gorm.io/gorm"
 )
 
@@ -11,11 +14,49 @@ type config struct {
 	db         *gorm.DB
 	addr       string
 	middleware []func(http.Handler) http.Handler
+
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	closeDB           bool
+
+	defaultPageSize int
+	maxPageSize     int
+	pagination      PaginationStyle
+	cursorModels    []any
+	cursorSecret    []byte
+	maxIncludeDepth int
+	readOnly        ReadOnlyPolicy
+
+	hooks hooks.Registry
 }
 
 // Option is a functional option for configuring the App.
 type Option func(*config)
 
+// PaginationStyle selects how List endpoints return pagination metadata.
+type PaginationStyle = blarhttp.PaginationStyle
+
+const (
+	// PaginationHeaders returns a bare JSON array with X-Total-Count and Link headers.
+	PaginationHeaders = blarhttp.PaginationHeaders
+	// PaginationEnvelope wraps results as {"data": [...], "meta": {...}, "links": {...}}.
+	PaginationEnvelope = blarhttp.PaginationEnvelope
+)
+
+// ReadOnlyPolicy selects how request bodies that set hidden, readonly or
+// aggregate fields are handled.
+type ReadOnlyPolicy = blarhttp.ReadOnlyPolicy
+
+const (
+	// ReadOnlyIgnore silently drops read-only fields from request bodies.
+	ReadOnlyIgnore = blarhttp.ReadOnlyIgnore
+	// ReadOnlyReject rejects request bodies with read-only fields with 400 Bad Request.
+	ReadOnlyReject = blarhttp.ReadOnlyReject
+)
+
 // WithDB sets the GORM database connection.
 func WithDB(db *gorm.DB) Option {
 	return func(c *config) {
@@ -37,10 +78,116 @@ func WithMiddleware(m func(http.Handler) http.Handler) Option {
 	}
 }
 
+// WithReadTimeout sets the maximum duration for reading an entire request.
+func WithReadTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.readTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading request headers.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.readHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of a response.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout sets how long keep-alive connections may stay idle.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.idleTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for in-flight requests on shutdown (default: 10s).
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithCloseDBOnShutdown closes the database connection pool when the app shuts down.
+func WithCloseDBOnShutdown() Option {
+	return func(c *config) {
+		c.closeDB = true
+	}
+}
+
+// WithDefaultPageSize sets the page size of List endpoints when none is requested (default: 20).
+// Sizes below 1 are ignored.
+func WithDefaultPageSize(n int) Option {
+	return func(c *config) {
+		if n >= 1 {
+			c.defaultPageSize = n
+		}
+	}
+}
+
+// WithMaxPageSize caps the page size clients may request (default: 100).
+// 0 removes the cap.
+func WithMaxPageSize(n int) Option {
+	return func(c *config) {
+		c.maxPageSize = n
+	}
+}
+
+// WithPagination sets how List endpoints return pagination metadata (default: PaginationHeaders).
+func WithPagination(style PaginationStyle) Option {
+	return func(c *config) {
+		c.pagination = style
+	}
+}
+
+// WithCursorPagination switches the List endpoints of the given models to
+// keyset pagination with opaque ?cursor= tokens, for tables too large for offsets.
+func WithCursorPagination(models ...any) Option {
+	return func(c *config) {
+		c.cursorModels = append(c.cursorModels, models...)
+	}
+}
+
+// WithCursorSecret sets the key used to sign pagination cursors.
+// Without it a random key is generated, so cursors do not survive restarts
+// and are not shared between instances.
+func WithCursorSecret(secret []byte) Option {
+	return func(c *config) {
+		c.cursorSecret = secret
+	}
+}
+
+// WithMaxIncludeDepth limits how many relations deep ?include= paths may go,
+// e.g. 2 allows include=items.product (default: 3).
+func WithMaxIncludeDepth(n int) Option {
+	return func(c *config) {
+		c.maxIncludeDepth = n
+	}
+}
+
+// WithReadOnlyPolicy sets how writes to hidden, readonly and aggregate
+// fields are handled (default: ReadOnlyIgnore).
+func WithReadOnlyPolicy(policy ReadOnlyPolicy) Option {
+	return func(c *config) {
+		c.readOnly = policy
+	}
+}
+
 // newConfig creates a new config with sensible defaults.
 func newConfig() *config {
 	return &config{
-		addr: ":8080",
+		addr:            ":8080",
+		shutdownTimeout: 10 * time.Second,
+		defaultPageSize: 20,
+		maxPageSize:     100,
+		pagination:      PaginationHeaders,
+		maxIncludeDepth: 3,
+		readOnly:        ReadOnlyIgnore,
 	}
 }
 
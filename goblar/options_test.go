@@ -2,7 +2,9 @@ package goblar
 
 import (
 	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -83,3 +85,111 @@ func TestNewConfig_Defaults(t *testing.T) {
 		t.Fatal("expected no middleware by default")
 	}
 }
+
+func TestServerTimeoutOptions(t *testing.T) {
+	cfg := newConfig()
+	cfg.apply(
+		WithReadTimeout(5*time.Second),
+		WithReadHeaderTimeout(2*time.Second),
+		WithWriteTimeout(10*time.Second),
+		WithIdleTimeout(time.Minute),
+		WithShutdownTimeout(3*time.Second),
+	)
+
+	if cfg.readTimeout != 5*time.Second {
+		t.Fatalf("expected read timeout 5s, got %s", cfg.readTimeout)
+	}
+	if cfg.readHeaderTimeout != 2*time.Second {
+		t.Fatalf("expected read header timeout 2s, got %s", cfg.readHeaderTimeout)
+	}
+	if cfg.writeTimeout != 10*time.Second {
+		t.Fatalf("expected write timeout 10s, got %s", cfg.writeTimeout)
+	}
+	if cfg.idleTimeout != time.Minute {
+		t.Fatalf("expected idle timeout 1m, got %s", cfg.idleTimeout)
+	}
+	if cfg.shutdownTimeout != 3*time.Second {
+		t.Fatalf("expected shutdown timeout 3s, got %s", cfg.shutdownTimeout)
+	}
+}
+
+func TestWithCloseDBOnShutdown(t *testing.T) {
+	cfg := newConfig()
+	if cfg.closeDB {
+		t.Fatal("expected closeDB to be false by default")
+	}
+
+	WithCloseDBOnShutdown()(cfg)
+	if !cfg.closeDB {
+		t.Fatal("expected closeDB to be set")
+	}
+}
+
+func TestPaginationOptions(t *testing.T) {
+	cfg := newConfig()
+	if cfg.defaultPageSize != 20 || cfg.maxPageSize != 100 || cfg.pagination != PaginationHeaders {
+		t.Fatalf("unexpected pagination defaults: %d %d %v", cfg.defaultPageSize, cfg.maxPageSize, cfg.pagination)
+	}
+
+	cfg.apply(
+		WithDefaultPageSize(50),
+		WithMaxPageSize(500),
+		WithPagination(PaginationEnvelope),
+	)
+
+	if cfg.defaultPageSize != 50 {
+		t.Fatalf("expected default page size 50, got %d", cfg.defaultPageSize)
+	}
+	if cfg.maxPageSize != 500 {
+		t.Fatalf("expected max page size 500, got %d", cfg.maxPageSize)
+	}
+	if cfg.pagination != PaginationEnvelope {
+		t.Fatal("expected envelope pagination")
+	}
+
+	cfg.apply(WithDefaultPageSize(0), WithDefaultPageSize(-5))
+	if cfg.defaultPageSize != 50 {
+		t.Fatalf("expected sizes below 1 to be ignored, got %d", cfg.defaultPageSize)
+	}
+}
+
+func TestCursorPaginationOptions(t *testing.T) {
+	cfg := newConfig()
+	cfg.apply(
+		WithCursorPagination(&TestEntity{}),
+		WithCursorSecret([]byte("secret")),
+	)
+
+	types := modelTypes(cfg.cursorModels)
+	if !types[reflect.TypeOf(TestEntity{})] {
+		t.Fatal("expected TestEntity to use cursor pagination")
+	}
+
+	if string(cfg.cursorSecret) != "secret" {
+		t.Fatal("expected cursor secret to be set")
+	}
+}
+
+func TestWithMaxIncludeDepth(t *testing.T) {
+	cfg := newConfig()
+	if cfg.maxIncludeDepth != 3 {
+		t.Fatalf("expected default include depth 3, got %d", cfg.maxIncludeDepth)
+	}
+
+	cfg.apply(WithMaxIncludeDepth(1))
+	if cfg.maxIncludeDepth != 1 {
+		t.Fatalf("expected include depth 1, got %d", cfg.maxIncludeDepth)
+	}
+}
+
+func TestWithReadOnlyPolicy(t *testing.T) {
+	cfg := newConfig()
+	if cfg.readOnly != ReadOnlyIgnore {
+		t.Fatal("expected readonly fields to be ignored by default")
+	}
+
+	cfg.apply(WithReadOnlyPolicy(ReadOnlyReject))
+	if cfg.readOnly != ReadOnlyReject {
+		t.Fatal("expected readonly fields to be rejected")
+	}
+}
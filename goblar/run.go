@@ -1,11 +1,95 @@
 package goblar
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Environment variables read by Run.
+const (
+	EnvAddr     = "GOBLAR_ADDR"      // Server address (default: ":8080")
+	EnvDSN      = "GOBLAR_DSN"       // SQLite data source name (default: "goblar.db")
+	EnvLogLevel = "GOBLAR_LOG_LEVEL" // GORM log level: silent, error, warn, info (default: "warn")
+)
+
+// defaultDSN is the SQLite database file used by Run when GOBLAR_DSN is unset.
+const defaultDSN = "goblar.db"
+
 // Run is the zero-brain entrypoint for go-blar.
 // It creates an App, registers models, and starts the server.
+// The server shuts down gracefully on SIGINT or SIGTERM.
+//
+// Run opens a SQLite database configured from the environment
+// (see EnvAddr, EnvDSN and EnvLogLevel) and closes it on shutdown.
 func Run(models ...any) error {
-	app := New()
+	env, err := readEnv()
+	if err != nil {
+		return err
+	}
+
+	db, err := gorm.Open(sqlite.Open(env.dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(env.logLevel),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open database %q: %w", env.dsn, err)
+	}
+
+	app := New(WithDB(db), WithAddress(env.addr), WithCloseDBOnShutdown())
 	if err := app.Register(models...); err != nil {
 		return err
 	}
-	return app.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return app.Run(ctx)
+}
+
+// envConfig holds the settings Run reads from the environment.
+type envConfig struct {
+	addr     string
+	dsn      string
+	logLevel logger.LogLevel
+}
+
+// readEnv reads Run's settings from the environment, falling back to defaults.
+func readEnv() (*envConfig, error) {
+	env := &envConfig{
+		addr:     newConfig().addr,
+		dsn:      defaultDSN,
+		logLevel: logger.Warn,
+	}
+
+	if addr := os.Getenv(EnvAddr); addr != "" {
+		env.addr = addr
+	}
+
+	if dsn := os.Getenv(EnvDSN); dsn != "" {
+		env.dsn = dsn
+	}
+
+	if level := os.Getenv(EnvLogLevel); level != "" {
+		switch strings.ToLower(level) {
+		case "silent":
+			env.logLevel = logger.Silent
+		case "error":
+			env.logLevel = logger.Error
+		case "warn":
+			env.logLevel = logger.Warn
+		case "info":
+			env.logLevel = logger.Info
+		default:
+			return nil, fmt.Errorf("invalid %s %q: must be silent, error, warn or info", EnvLogLevel, level)
+		}
+	}
+
+	return env, nil
 }
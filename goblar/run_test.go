@@ -0,0 +1,61 @@
+package goblar
+
+import (
+	"testing"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestReadEnvDefaults(t *testing.T) {
+	t.Setenv(EnvAddr, "")
+	t.Setenv(EnvDSN, "")
+	t.Setenv(EnvLogLevel, "")
+
+	env, err := readEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if env.addr != ":8080" {
+		t.Fatalf("expected default address :8080, got %s", env.addr)
+	}
+
+	if env.dsn != defaultDSN {
+		t.Fatalf("expected default DSN %s, got %s", defaultDSN, env.dsn)
+	}
+
+	if env.logLevel != logger.Warn {
+		t.Fatalf("expected default log level warn, got %v", env.logLevel)
+	}
+}
+
+func TestReadEnvOverrides(t *testing.T) {
+	t.Setenv(EnvAddr, ":9090")
+	t.Setenv(EnvDSN, "file::memory:")
+	t.Setenv(EnvLogLevel, "INFO")
+
+	env, err := readEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if env.addr != ":9090" {
+		t.Fatalf("expected address :9090, got %s", env.addr)
+	}
+
+	if env.dsn != "file::memory:" {
+		t.Fatalf("expected DSN file::memory:, got %s", env.dsn)
+	}
+
+	if env.logLevel != logger.Info {
+		t.Fatalf("expected log level info, got %v", env.logLevel)
+	}
+}
+
+func TestReadEnvInvalidLogLevel(t *testing.T) {
+	t.Setenv(EnvLogLevel, "verbose")
+
+	if _, err := readEnv(); err == nil {
+		t.Fatal("expected error for invalid log level")
+	}
+}
@@ -0,0 +1,41 @@
+package goblar
+
+import "github.com/kamil5b/go-blar/internal/validate"
+
+// ValidationFunc checks a field value against a validation rule, e.g. one
+// selected with go-blar:"validate:slug" or go-blar:"validate:divisible=5".
+// param is the text after "=", pointers are dereferenced, and the returned
+// error's message is reported for the field, e.g. "must be a slug".
+type ValidationFunc = validate.Func
+
+// RegisterValidator registers a validation rule under name, replacing any
+// previous one, including the built-in rules.
+//
+//	goblar.RegisterValidator("slug", func(ctx context.Context, value any, param string) error {
+//		if !slugPattern.MatchString(value.(string)) {
+//			return errors.New("must be a slug")
+//		}
+//		return nil
+//	})
+func RegisterValidator(name string, fn ValidationFunc) {
+	validate.Register(name, fn)
+}
+
+// ValidationErrors lists invalid fields. Returned from Validate, or from a
+// hook, it is reported as a 422 Unprocessable Entity listing the fields.
+type ValidationErrors = validate.Errors
+
+// Validator is implemented by entities with rules that span several fields or
+// need database lookups. Validate is called on Create, PUT and PATCH after the
+// validate: tag rules, even when they fail, inside the write's transaction and
+// before the BeforeCreate/BeforeUpdate hooks. Its errors are reported together
+// with those of the tag rules.
+//
+//	func (b *Booking) Validate(ctx context.Context, tx *gorm.DB) goblar.ValidationErrors {
+//		var errs goblar.ValidationErrors
+//		if !b.End.After(b.Start) {
+//			errs = append(errs, goblar.FieldError{Field: "end", Message: "must be after start"})
+//		}
+//		return errs
+//	}
+type Validator = validate.Validator
@@ -0,0 +1,169 @@
+package goblar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Batch uses a custom validation rule with a parameter.
+type Batch struct {
+	ID   uint
+	Size int `go-blar:"validate:divisible=5"`
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("divisible", func(ctx context.Context, value any, param string) error {
+		if param != "5" {
+			return errors.New("unexpected parameter " + param)
+		}
+		if value.(int)%5 != 0 {
+			return errors.New("must be divisible by 5")
+		}
+		return nil
+	})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := New(WithDB(db))
+	if err := app.Register(&Batch{}); err != nil {
+		t.Fatal(err)
+	}
+	router := app.Handler()
+
+	for body, status := range map[string]int{`{"Size":10}`: http.StatusCreated, `{"Size":7}`: http.StatusUnprocessableEntity} {
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", body, status, w.Code, w.Body.String())
+		}
+		if status == http.StatusUnprocessableEntity && !strings.Contains(w.Body.String(), "must be divisible by 5") {
+			t.Errorf("%s: expected the rule's message, got %s", body, w.Body.String())
+		}
+	}
+}
+
+// Event validates its dates against each other and its venue in the database.
+type Event struct {
+	ID      uint
+	Title   string `json:"title" go-blar:"validate:required"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	VenueID uint   `json:"venue_id"`
+}
+
+// Venue is referenced by Event.
+type Venue struct {
+	ID     uint
+	Active bool
+}
+
+func (e *Event) Validate(ctx context.Context, tx *gorm.DB) ValidationErrors {
+	var errs ValidationErrors
+	if e.End <= e.Start {
+		errs = append(errs, FieldError{Field: "end", Message: "must be after start"})
+	}
+	var active int64
+	if err := tx.Model(&Venue{}).Where("id = ? AND active", e.VenueID).Count(&active).Error; err != nil || active == 0 {
+		errs = append(errs, FieldError{Field: "venue_id", Message: "must be an active venue"})
+	}
+	return errs
+}
+
+func TestValidator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Venue{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]Venue{{Active: true}, {Active: false}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(WithDB(db))
+	if err := app.Register(&Event{}); err != nil {
+		t.Fatal(err)
+	}
+	router := app.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/event", strings.NewReader(`{"start":2,"end":1,"venue_id":2}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var problem struct {
+		Errors ValidationErrors `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := ValidationErrors{
+		{Field: "title", Message: "is required"},
+		{Field: "end", Message: "must be after start"},
+		{Field: "venue_id", Message: "must be an active venue"},
+	}
+	if !reflect.DeepEqual(problem.Errors, want) {
+		t.Fatalf("expected errors %v, got %v", want, problem.Errors)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/event", strings.NewReader(`{"title":"launch","start":1,"end":2,"venue_id":1}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Shelf is referenced by Tome through an fk: tag without a relation.
+type Shelf struct {
+	ID uint
+}
+
+type Tome struct {
+	ID      uint
+	ShelfID uint `json:"shelf_id" go-blar:"fk:Shelf"`
+}
+
+func TestRegisterResolvesForeignKeys(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The referencing entity may be registered first
+	app := New(WithDB(db))
+	if err := app.Register(&Tome{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Register(&Shelf{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Shelf{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	router := app.Handler()
+
+	for body, status := range map[string]int{`{"shelf_id":1}`: http.StatusCreated, `{"shelf_id":2}`: http.StatusUnprocessableEntity} {
+		req := httptest.NewRequest(http.MethodPost, "/tome", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", body, status, w.Code, w.Body.String())
+		}
+	}
+}
@@ -1,14 +1,382 @@
 package aggregate
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/kamil5b/go-blar/internal/meta"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 // ComputeAggregates computes aggregate values (count, sum, etc.) for entities.
 // This is called internally when loading related data.
-func ComputeAggregates(db *gorm.DB, entity any, aggregates []*meta.AggregateMeta) error {
-	// TODO: Implement aggregate computation
-	// This will handle count:, sum:, and other aggregate directives
+//
+// entities is a pointer to a struct or to a slice of structs. Each aggregate is
+// computed with a single grouped query over its relation for all entities at once.
+func ComputeAggregates(db *gorm.DB, entities any, aggregates []*meta.AggregateMeta) error {
+	if len(aggregates) == 0 {
+		return nil
+	}
+
+	rows := structValues(entities)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(rows[0].Addr().Interface()); err != nil {
+		return fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	for _, agg := range aggregates {
+		if err := compute(db, stmt.Schema, rows, agg); err != nil {
+			return fmt.Errorf("failed to compute aggregate %s: %w", agg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// compute runs one aggregate query grouped by owner and assigns the results.
+func compute(db *gorm.DB, sch *schema.Schema, rows []reflect.Value, agg *meta.AggregateMeta) error {
+	rel, ok := sch.Relationships.Relations[agg.Relation]
+	if !ok || (rel.Type != schema.HasMany && rel.Type != schema.HasOne) {
+		return fmt.Errorf("%q is not a has-many relation of %s", agg.Relation, sch.Name)
+	}
+
+	selectExpr, err := expression(db, rel.FieldSchema, agg)
+	if err != nil {
+		return err
+	}
+
+	query := db.Model(reflect.New(rel.FieldSchema.ModelType).Interface())
+
+	if agg.Filter != "" {
+		condition, args, err := translate(db, rel.FieldSchema, agg.Relation, agg.Filter, true)
+		if err != nil {
+			return err
+		}
+		query = query.Where("("+condition+")", args...)
+	}
+
+	// Find the owner key and any fixed conditions (e.g. polymorphic type)
+	var owner *schema.Reference
+	for _, ref := range rel.References {
+		if ref.OwnPrimaryKey && ref.PrimaryKey != nil {
+			owner = ref
+		} else if ref.PrimaryValue != "" {
+			query = query.Where(clause.Eq{Column: clause.Column{Name: ref.ForeignKey.DBName}, Value: ref.PrimaryValue})
+		}
+	}
+	if owner == nil {
+		return fmt.Errorf("relation %q has no owner key", agg.Relation)
+	}
+
+	keys := make([]any, 0, len(rows))
+	for _, row := range rows {
+		key, _ := owner.PrimaryKey.ValueOf(db.Statement.Context, row)
+		keys = append(keys, key)
+	}
+
+	foreignKey := db.Statement.Quote(owner.ForeignKey.DBName)
+	result, err := query.
+		Select(foreignKey + " AS owner_key, " + selectExpr + " AS aggregate_value").
+		Where(clause.IN{Column: clause.Column{Name: owner.ForeignKey.DBName}, Values: keys}).
+		Group(foreignKey).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+
+	values := make(map[string]any, len(rows))
+	for result.Next() {
+		var ownerKey, value any
+		if err := result.Scan(&ownerKey, &value); err != nil {
+			return err
+		}
+		values[keyString(ownerKey)] = value
+	}
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	// Owners without related rows get the zero value
+	for i, row := range rows {
+		field := row.FieldByName(agg.Name)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("field %s is not settable", agg.Name)
+		}
+		if err := assign(field, values[keyString(keys[i])]); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// functions maps aggregate types to their SQL functions.
+var functions = map[string]string{
+	"count":         "COUNT(%s)",
+	"countDistinct": "COUNT(DISTINCT %s)",
+	"sum":           "SUM(%s)",
+	"avg":           "AVG(%s)",
+	"min":           "MIN(%s)",
+	"max":           "MAX(%s)",
+}
+
+// expression builds the SQL aggregate expression for an aggregate directive.
+func expression(db *gorm.DB, child *schema.Schema, agg *meta.AggregateMeta) (string, error) {
+	function, ok := functions[agg.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported aggregate type %q", agg.Type)
+	}
+
+	if agg.Field == agg.Relation {
+		if agg.Type == "count" {
+			return "COUNT(*)", nil
+		}
+		return "", fmt.Errorf("%s requires a field, e.g. %s:%s.Price", agg.Type, agg.Type, agg.Relation)
+	}
+
+	operand, _, err := translate(db, child, agg.Relation, agg.Field, false)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(function, operand), nil
+}
+
+// translate converts an aggregate expression such as "Items.Price*Items.Quantity"
+// into SQL over the related table's quoted column names.
+// Only field references, numeric literals, arithmetic operators and parentheses are allowed.
+//
+// With filter set, it also accepts comparisons, AND/OR/NOT/IS/NULL, TRUE/FALSE,
+// single-quoted strings and bare field names ("Quantity>0"). String and boolean
+// literals are returned as bind arguments.
+func translate(db *gorm.DB, child *schema.Schema, relation, expr string, filter bool) (string, []any, error) {
+	if strings.Contains(expr, "--") || strings.Contains(expr, "/*") {
+		return "", nil, fmt.Errorf("comments are not allowed in expression %q", expr)
+	}
+
+	var sql strings.Builder
+	var args []any
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || strings.ContainsRune("+-*/()", r):
+			sql.WriteRune(r)
+			i++
+		case filter && strings.ContainsRune("=!<>", r):
+			start := i
+			for i < len(runes) && strings.ContainsRune("=!<>", runes[i]) {
+				i++
+			}
+			op := string(runes[start:i])
+			if !slices.Contains([]string{"=", "!=", "<>", "<", "<=", ">", ">="}, op) {
+				return "", nil, fmt.Errorf("invalid operator %q in %q", op, expr)
+			}
+			sql.WriteString(op)
+		case filter && r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return "", nil, fmt.Errorf("unterminated string in %q", expr)
+			}
+			sql.WriteString("?")
+			args = append(args, string(runes[i+1:end]))
+			i = end + 1
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			sql.WriteString(string(runes[start:i]))
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			path := string(runes[start:i])
+
+			if filter {
+				switch strings.ToUpper(path) {
+				case "AND", "OR", "NOT", "IS", "NULL":
+					sql.WriteString(strings.ToUpper(path))
+					continue
+				case "TRUE", "FALSE":
+					sql.WriteString("?")
+					args = append(args, strings.EqualFold(path, "true"))
+					continue
+				}
+			}
+
+			name := path
+			if rel, rest, ok := strings.Cut(path, "."); ok && rel == relation {
+				name = rest
+			} else if !filter {
+				return "", nil, fmt.Errorf("invalid field reference %q: expected %s.<Field>", path, relation)
+			}
+			if strings.Contains(name, ".") {
+				return "", nil, fmt.Errorf("invalid field reference %q: expected %s.<Field>", path, relation)
+			}
+
+			field := child.LookUpField(name)
+			if field == nil || field.DBName == "" {
+				return "", nil, fmt.Errorf("unknown field %q on %s", name, child.Name)
+			}
+			sql.WriteString(db.Statement.Quote(field.DBName))
+		default:
+			return "", nil, fmt.Errorf("invalid character %q in expression %q", r, expr)
+		}
+	}
+
+	return sql.String(), args, nil
+}
+
+// structValues returns the addressable struct values held by a pointer to a struct or slice.
+func structValues(entities any) []reflect.Value {
+	v := reflect.ValueOf(entities)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{v}
+	case reflect.Slice:
+		values := make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			values = append(values, elem)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// keyString normalizes a key value so driver and Go types compare equal.
+func keyString(v any) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// assign stores a scanned aggregate value in the destination field.
+// A nil value (no related rows) sets the zero value, or nil for pointer fields.
+func assign(dst reflect.Value, src any) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		ptr := reflect.New(dst.Type().Elem())
+		if err := assign(ptr.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := src.(int64); ok {
+			dst.SetInt(n)
+			return nil
+		}
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := src.(int64); ok && n >= 0 {
+			dst.SetUint(uint64(n))
+			return nil
+		}
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	default:
+		if str, ok := src.(string); ok && dst.Type() == reflect.TypeOf(time.Time{}) {
+			t, err := parseTime(str)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+
+		sv := reflect.ValueOf(src)
+		if !sv.Type().ConvertibleTo(dst.Type()) {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		dst.Set(sv.Convert(dst.Type()))
+	}
+
+	return nil
+}
+
+// toFloat converts a numeric driver value to float64.
+func toFloat(v any) (float64, error) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to a number", v)
+	}
+}
+
+// timeLayouts are the layouts drivers use when MIN/MAX return times as text.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// parseTime parses a time returned as text by the driver.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time", s)
+}
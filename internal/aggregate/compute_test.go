@@ -0,0 +1,241 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Item struct {
+	ID       uint
+	OrderID  uint
+	Price    float64
+	Quantity int
+}
+
+type Order struct {
+	ID        uint
+	Name      string
+	Items     []Item  `go-blar:"list"`
+	ItemCount int     `go-blar:"count:Items" gorm:"-"`
+	ItemQty   uint    `go-blar:"sum:Items.Quantity" gorm:"-"`
+	Total     float64 `go-blar:"sum:Items.Price*Items.Quantity" gorm:"-"`
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Order{}, &Item{}); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+func seedOrders(t *testing.T, db *gorm.DB) {
+	orders := []Order{
+		{Name: "first", Items: []Item{{Price: 2.5, Quantity: 2}, {Price: 10, Quantity: 1}}},
+		{Name: "second", Items: []Item{{Price: 1, Quantity: 4}}},
+		{Name: "empty"},
+	}
+	if err := db.Create(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComputeAggregatesList(t *testing.T) {
+	db := setupTestDB(t)
+	seedOrders(t, db)
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var orders []Order
+	if err := db.Order("id").Find(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ComputeAggregates(db, &orders, entityMeta.Aggregates); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []struct {
+		count int
+		qty   uint
+		total float64
+	}{
+		{2, 3, 15},
+		{1, 4, 4},
+		{0, 0, 0},
+	}
+
+	for i, e := range expected {
+		o := orders[i]
+		if o.ItemCount != e.count || o.ItemQty != e.qty || o.Total != e.total {
+			t.Errorf("order %s: expected count=%d qty=%d total=%v, got count=%d qty=%d total=%v",
+				o.Name, e.count, e.qty, e.total, o.ItemCount, o.ItemQty, o.Total)
+		}
+	}
+}
+
+func TestComputeAggregatesSingle(t *testing.T) {
+	db := setupTestDB(t)
+	seedOrders(t, db)
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order Order
+	if err := db.First(&order, 2).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ComputeAggregates(db, &order, entityMeta.Aggregates); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if order.ItemCount != 1 || order.Total != 4 {
+		t.Fatalf("expected count=1 total=4, got count=%d total=%v", order.ItemCount, order.Total)
+	}
+}
+
+func TestComputeAggregatesInvalidExpression(t *testing.T) {
+	db := setupTestDB(t)
+	seedOrders(t, db)
+
+	tests := []*meta.AggregateMeta{
+		{Name: "Total", Type: "sum", Relation: "Items", Field: "Items.Price; DROP TABLE items"},
+		{Name: "Total", Type: "sum", Relation: "Items", Field: "Items.Unknown"},
+		{Name: "Total", Type: "sum", Relation: "Items", Field: "Items"},
+		{Name: "ItemCount", Type: "count", Relation: "Missing", Field: "Missing"},
+	}
+
+	for _, agg := range tests {
+		var orders []Order
+		if err := db.Find(&orders).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ComputeAggregates(db, &orders, []*meta.AggregateMeta{agg}); err == nil {
+			t.Errorf("expected error for %s:%s", agg.Type, agg.Field)
+		}
+	}
+}
+
+func TestComputeAggregatesStatistics(t *testing.T) {
+	type Line struct {
+		ID       uint
+		BasketID uint
+		SKU      string
+		Price    float64
+		Quantity int
+	}
+
+	type Basket struct {
+		ID          uint
+		Name        string
+		Lines       []Line
+		AvgPrice    float64  `go-blar:"avg:Lines.Price" gorm:"-"`
+		MinPrice    *float64 `go-blar:"min:Lines.Price" gorm:"-"`
+		MaxPrice    float64  `go-blar:"max:Lines.Price" gorm:"-"`
+		DistinctSKU int      `go-blar:"countDistinct:Lines.SKU" gorm:"-"`
+		InStock     int      `go-blar:"count:Lines where Quantity>0" gorm:"-"`
+		CheapStock  int      `go-blar:"sum:Lines.Quantity where Lines.Price < 5 AND SKU != 'x'" gorm:"-"`
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Basket{}, &Line{}); err != nil {
+		t.Fatal(err)
+	}
+
+	baskets := []Basket{
+		{Name: "full", Lines: []Line{
+			{SKU: "a", Price: 2, Quantity: 1},
+			{SKU: "a", Price: 4, Quantity: 0},
+			{SKU: "b", Price: 9, Quantity: 3},
+			{SKU: "x", Price: 1, Quantity: 7},
+		}},
+		{Name: "empty"},
+	}
+	if err := db.Create(&baskets).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Basket{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded []Basket
+	if err := db.Order("id").Find(&loaded).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := ComputeAggregates(db, &loaded, entityMeta.Aggregates); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	full := loaded[0]
+	if full.AvgPrice != 4 {
+		t.Errorf("expected avg 4, got %v", full.AvgPrice)
+	}
+	if full.MinPrice == nil || *full.MinPrice != 1 {
+		t.Errorf("expected min 1, got %v", full.MinPrice)
+	}
+	if full.MaxPrice != 9 {
+		t.Errorf("expected max 9, got %v", full.MaxPrice)
+	}
+	if full.DistinctSKU != 3 {
+		t.Errorf("expected 3 distinct SKUs, got %d", full.DistinctSKU)
+	}
+	if full.InStock != 3 {
+		t.Errorf("expected 3 lines in stock, got %d", full.InStock)
+	}
+	if full.CheapStock != 1 {
+		t.Errorf("expected cheap stock 1, got %d", full.CheapStock)
+	}
+
+	empty := loaded[1]
+	if empty.AvgPrice != 0 || empty.MinPrice != nil || empty.MaxPrice != 0 || empty.DistinctSKU != 0 || empty.InStock != 0 {
+		t.Errorf("expected zero values for empty relation, got %+v", empty)
+	}
+}
+
+func TestComputeAggregatesInvalidFilter(t *testing.T) {
+	db := setupTestDB(t)
+	seedOrders(t, db)
+
+	filters := []string{
+		"Quantity > 0; DROP TABLE items",
+		"Quantity > 0 -- comment",
+		"Unknown = 1",
+		"Quantity =< 1",
+		"Name = 'unterminated",
+	}
+
+	for _, filter := range filters {
+		var orders []Order
+		if err := db.Find(&orders).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		agg := &meta.AggregateMeta{Name: "ItemCount", Type: "count", Relation: "Items", Field: "Items", Filter: filter}
+		if err := ComputeAggregates(db, &orders, []*meta.AggregateMeta{agg}); err == nil {
+			t.Errorf("expected error for filter %q", filter)
+		}
+	}
+}
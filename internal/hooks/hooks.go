@@ -3,13 +3,64 @@ package hooks
 import (
 	"context"
 
-	"github.com/kamil5b/go-blar/goblar"
 	"gorm.io/gorm"
 )
 
+// BeforeCreate is called before an entity is created.
+type BeforeCreate interface {
+	BeforeCreate(ctx context.Context, tx *gorm.DB) error
+}
+
+// AfterCreate is called after an entity is created.
+type AfterCreate interface {
+	AfterCreate(ctx context.Context, tx *gorm.DB) error
+}
+
+// BeforeUpdate is called before an entity is updated.
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, tx *gorm.DB) error
+}
+
+// AfterUpdate is called after an entity is updated.
+type AfterUpdate interface {
+	AfterUpdate(ctx context.Context, tx *gorm.DB) error
+}
+
+// BeforeDelete is called before an entity is deleted.
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context, tx *gorm.DB) error
+}
+
+// AfterDelete is called after an entity is deleted.
+type AfterDelete interface {
+	AfterDelete(ctx context.Context, tx *gorm.DB) error
+}
+
+// BeforeList is called on a zero entity before a list query. It returns the
+// query to run, e.g. scoped to the current tenant.
+type BeforeList interface {
+	BeforeList(ctx context.Context, db *gorm.DB) (*gorm.DB, error)
+}
+
+// AfterList is called on each entity of a list page after it is loaded.
+type AfterList interface {
+	AfterList(ctx context.Context, tx *gorm.DB) error
+}
+
+// BeforeGet is called on a zero entity before a single entity is fetched.
+// It returns the query to run, e.g. scoped to the current tenant.
+type BeforeGet interface {
+	BeforeGet(ctx context.Context, db *gorm.DB) (*gorm.DB, error)
+}
+
+// AfterGet is called after a single entity is fetched.
+type AfterGet interface {
+	AfterGet(ctx context.Context, tx *gorm.DB) error
+}
+
 // CallBeforeCreate calls the BeforeCreate hook on the entity if it implements it.
 func CallBeforeCreate(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.BeforeCreate); ok {
+	if h, ok := entity.(BeforeCreate); ok {
 		return h.BeforeCreate(ctx, tx)
 	}
 	return nil
@@ -17,7 +68,7 @@ func CallBeforeCreate(ctx context.Context, entity any, tx *gorm.DB) error {
 
 // CallAfterCreate calls the AfterCreate hook on the entity if it implements it.
 func CallAfterCreate(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.AfterCreate); ok {
+	if h, ok := entity.(AfterCreate); ok {
 		return h.AfterCreate(ctx, tx)
 	}
 	return nil
@@ -25,7 +76,7 @@ func CallAfterCreate(ctx context.Context, entity any, tx *gorm.DB) error {
 
 // CallBeforeUpdate calls the BeforeUpdate hook on the entity if it implements it.
 func CallBeforeUpdate(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.BeforeUpdate); ok {
+	if h, ok := entity.(BeforeUpdate); ok {
 		return h.BeforeUpdate(ctx, tx)
 	}
 	return nil
@@ -33,7 +84,7 @@ func CallBeforeUpdate(ctx context.Context, entity any, tx *gorm.DB) error {
 
 // CallAfterUpdate calls the AfterUpdate hook on the entity if it implements it.
 func CallAfterUpdate(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.AfterUpdate); ok {
+	if h, ok := entity.(AfterUpdate); ok {
 		return h.AfterUpdate(ctx, tx)
 	}
 	return nil
@@ -41,7 +92,7 @@ func CallAfterUpdate(ctx context.Context, entity any, tx *gorm.DB) error {
 
 // CallBeforeDelete calls the BeforeDelete hook on the entity if it implements it.
 func CallBeforeDelete(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.BeforeDelete); ok {
+	if h, ok := entity.(BeforeDelete); ok {
 		return h.BeforeDelete(ctx, tx)
 	}
 	return nil
@@ -49,8 +100,42 @@ func CallBeforeDelete(ctx context.Context, entity any, tx *gorm.DB) error {
 
 // CallAfterDelete calls the AfterDelete hook on the entity if it implements it.
 func CallAfterDelete(ctx context.Context, entity any, tx *gorm.DB) error {
-	if h, ok := entity.(goblar.AfterDelete); ok {
+	if h, ok := entity.(AfterDelete); ok {
 		return h.AfterDelete(ctx, tx)
 	}
 	return nil
 }
+
+// CallBeforeList calls the BeforeList hook on the entity if it implements it,
+// returning the scoped query, or db unchanged otherwise.
+func CallBeforeList(ctx context.Context, entity any, db *gorm.DB) (*gorm.DB, error) {
+	if h, ok := entity.(BeforeList); ok {
+		return h.BeforeList(ctx, db)
+	}
+	return db, nil
+}
+
+// CallAfterList calls the AfterList hook on the entity if it implements it.
+func CallAfterList(ctx context.Context, entity any, tx *gorm.DB) error {
+	if h, ok := entity.(AfterList); ok {
+		return h.AfterList(ctx, tx)
+	}
+	return nil
+}
+
+// CallBeforeGet calls the BeforeGet hook on the entity if it implements it,
+// returning the scoped query, or db unchanged otherwise.
+func CallBeforeGet(ctx context.Context, entity any, db *gorm.DB) (*gorm.DB, error) {
+	if h, ok := entity.(BeforeGet); ok {
+		return h.BeforeGet(ctx, db)
+	}
+	return db, nil
+}
+
+// CallAfterGet calls the AfterGet hook on the entity if it implements it.
+func CallAfterGet(ctx context.Context, entity any, tx *gorm.DB) error {
+	if h, ok := entity.(AfterGet); ok {
+		return h.AfterGet(ctx, tx)
+	}
+	return nil
+}
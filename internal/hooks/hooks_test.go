@@ -219,3 +219,58 @@ func TestMultipleHooks(t *testing.T) {
 		t.Fatal("expected all hooks to be called")
 	}
 }
+
+// scopedDB stands in for the query returned by Reader.BeforeList.
+var scopedDB = &gorm.DB{}
+
+// Reader implements the read hooks.
+type Reader struct {
+	ID     uint
+	Loaded bool
+}
+
+func (r *Reader) BeforeList(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return scopedDB, nil
+}
+
+func (r *Reader) AfterList(ctx context.Context, tx *gorm.DB) error {
+	r.Loaded = true
+	return nil
+}
+
+func (r *Reader) BeforeGet(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return nil, errors.New("before get failed")
+}
+
+func (r *Reader) AfterGet(ctx context.Context, tx *gorm.DB) error {
+	r.Loaded = true
+	return nil
+}
+
+func TestCallReadHooks(t *testing.T) {
+	ctx := context.Background()
+	db := &gorm.DB{}
+
+	if scoped, err := CallBeforeList(ctx, &Reader{}, db); err != nil || scoped != scopedDB {
+		t.Fatalf("expected a scoped query, got %v, %v", scoped, err)
+	}
+	if scoped, err := CallBeforeList(ctx, &SimpleEntity{}, db); err != nil || scoped != db {
+		t.Fatalf("expected the query unchanged, got %v, %v", scoped, err)
+	}
+	if _, err := CallBeforeGet(ctx, &Reader{}, db); err == nil {
+		t.Fatal("expected BeforeGet error")
+	}
+
+	entity := &Reader{}
+	if err := CallAfterGet(ctx, entity, nil); err != nil || !entity.Loaded {
+		t.Fatal("expected AfterGet to be called")
+	}
+
+	entity = &Reader{}
+	if err := CallAfterList(ctx, entity, nil); err != nil || !entity.Loaded {
+		t.Fatal("expected AfterList to be called")
+	}
+	if err := CallAfterList(ctx, &SimpleEntity{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
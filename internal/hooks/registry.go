@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Event names a lifecycle event hooks can be registered for.
+type Event string
+
+const (
+	OnBeforeCreate Event = "BeforeCreate"
+	OnAfterCreate  Event = "AfterCreate"
+	OnBeforeUpdate Event = "BeforeUpdate"
+	OnAfterUpdate  Event = "AfterUpdate"
+	OnBeforeDelete Event = "BeforeDelete"
+	OnAfterDelete  Event = "AfterDelete"
+	OnAfterGet     Event = "AfterGet"
+	OnAfterList    Event = "AfterList"
+)
+
+// Func is a hook registered for an event rather than implemented as a method.
+// entity is a pointer to the entity struct.
+type Func func(ctx context.Context, entity any, tx *gorm.DB) error
+
+// Registry holds the hooks registered for all entities and for single entity
+// types. The zero value is empty and ready to use; a nil *Registry only runs
+// method hooks.
+type Registry struct {
+	global map[Event][]Func
+	typed  map[reflect.Type]map[Event][]Func
+}
+
+// Add registers fn for event on entities of type t, a struct type, or on all
+// entities if t is nil. Hooks for the same event run in registration order.
+func (r *Registry) Add(event Event, t reflect.Type, fn Func) {
+	if t == nil {
+		if r.global == nil {
+			r.global = make(map[Event][]Func)
+		}
+		r.global[event] = append(r.global[event], fn)
+		return
+	}
+
+	if r.typed == nil {
+		r.typed = make(map[reflect.Type]map[Event][]Func)
+	}
+	if r.typed[t] == nil {
+		r.typed[t] = make(map[Event][]Func)
+	}
+	r.typed[t][event] = append(r.typed[t][event], fn)
+}
+
+// Call runs the hooks for event on entity, a pointer to a struct, and stops at
+// the first error. Before hooks run from the outside in: global hooks, then
+// hooks for the entity type, then the entity's method hook. After hooks run
+// in the reverse order: method, entity type, global.
+func (r *Registry) Call(ctx context.Context, event Event, entity any, tx *gorm.DB) error {
+	var global, typed []Func
+	if r != nil {
+		global = r.global[event]
+		typed = r.typed[reflect.TypeOf(entity).Elem()][event]
+	}
+
+	if strings.HasPrefix(string(event), "Before") {
+		if err := callFuncs(ctx, global, entity, tx); err != nil {
+			return err
+		}
+		if err := callFuncs(ctx, typed, entity, tx); err != nil {
+			return err
+		}
+		return callMethod(ctx, event, entity, tx)
+	}
+
+	if err := callMethod(ctx, event, entity, tx); err != nil {
+		return err
+	}
+	if err := callFuncs(ctx, typed, entity, tx); err != nil {
+		return err
+	}
+	return callFuncs(ctx, global, entity, tx)
+}
+
+// CallEach runs Call for each entity of entities, a pointer to a slice of
+// structs or struct pointers.
+func (r *Registry) CallEach(ctx context.Context, event Event, entities any, tx *gorm.DB) error {
+	v := reflect.ValueOf(entities).Elem()
+	for i := 0; i < v.Len(); i++ {
+		entity := v.Index(i)
+		if entity.Kind() != reflect.Ptr {
+			entity = entity.Addr()
+		}
+		if err := r.Call(ctx, event, entity.Interface(), tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callMethod calls the entity's method hook for event, if it implements it.
+func callMethod(ctx context.Context, event Event, entity any, tx *gorm.DB) error {
+	switch event {
+	case OnBeforeCreate:
+		return CallBeforeCreate(ctx, entity, tx)
+	case OnAfterCreate:
+		return CallAfterCreate(ctx, entity, tx)
+	case OnBeforeUpdate:
+		return CallBeforeUpdate(ctx, entity, tx)
+	case OnAfterUpdate:
+		return CallAfterUpdate(ctx, entity, tx)
+	case OnBeforeDelete:
+		return CallBeforeDelete(ctx, entity, tx)
+	case OnAfterDelete:
+		return CallAfterDelete(ctx, entity, tx)
+	case OnAfterGet:
+		return CallAfterGet(ctx, entity, tx)
+	case OnAfterList:
+		return CallAfterList(ctx, entity, tx)
+	}
+	return nil
+}
+
+// callFuncs calls registered hooks in order and stops at the first error.
+func callFuncs(ctx context.Context, fns []Func, entity any, tx *gorm.DB) error {
+	for _, fn := range fns {
+		if err := fn(ctx, entity, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// Traced records the hooks called on it.
+type Traced struct {
+	Calls []string
+}
+
+func (t *Traced) BeforeCreate(ctx context.Context, tx *gorm.DB) error {
+	t.Calls = append(t.Calls, "method")
+	return nil
+}
+
+func (t *Traced) AfterCreate(ctx context.Context, tx *gorm.DB) error {
+	t.Calls = append(t.Calls, "method")
+	return nil
+}
+
+// trace returns a hook recording name on a *Traced.
+func trace(name string) Func {
+	return func(ctx context.Context, entity any, tx *gorm.DB) error {
+		if t, ok := entity.(*Traced); ok {
+			t.Calls = append(t.Calls, name)
+		}
+		return nil
+	}
+}
+
+func TestRegistryOrder(t *testing.T) {
+	ctx := context.Background()
+	tracedType := reflect.TypeOf(Traced{})
+
+	var r Registry
+	r.Add(OnBeforeCreate, tracedType, trace("typed"))
+	r.Add(OnBeforeCreate, nil, trace("global 1"))
+	r.Add(OnBeforeCreate, nil, trace("global 2"))
+	r.Add(OnAfterCreate, nil, trace("global"))
+	r.Add(OnAfterCreate, tracedType, trace("typed"))
+	r.Add(OnBeforeCreate, reflect.TypeOf(SimpleEntity{}), trace("other"))
+
+	entity := &Traced{}
+	if err := r.Call(ctx, OnBeforeCreate, entity, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"global 1", "global 2", "typed", "method"}; !slices.Equal(entity.Calls, want) {
+		t.Fatalf("expected before hooks %v, got %v", want, entity.Calls)
+	}
+
+	entity = &Traced{}
+	if err := r.Call(ctx, OnAfterCreate, entity, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"method", "typed", "global"}; !slices.Equal(entity.Calls, want) {
+		t.Fatalf("expected after hooks %v, got %v", want, entity.Calls)
+	}
+
+	// A nil registry only runs method hooks
+	entity = &Traced{}
+	if err := (*Registry)(nil).Call(ctx, OnBeforeCreate, entity, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"method"}; !slices.Equal(entity.Calls, want) {
+		t.Fatalf("expected method hook only, got %v", entity.Calls)
+	}
+}
+
+func TestRegistryStopsOnError(t *testing.T) {
+	ctx := context.Background()
+
+	var r Registry
+	r.Add(OnBeforeCreate, nil, func(ctx context.Context, entity any, tx *gorm.DB) error {
+		return errors.New("denied")
+	})
+	r.Add(OnBeforeCreate, nil, trace("global"))
+
+	entity := &Traced{}
+	if err := r.Call(ctx, OnBeforeCreate, entity, nil); err == nil || err.Error() != "denied" {
+		t.Fatalf("expected denied error, got %v", err)
+	}
+	if len(entity.Calls) != 0 {
+		t.Fatalf("expected no hooks after the error, got %v", entity.Calls)
+	}
+}
+
+func TestRegistryCallEach(t *testing.T) {
+	var r Registry
+	r.Add(OnAfterList, nil, trace("global"))
+
+	values := []Traced{{}, {}}
+	pointers := []*Traced{{}}
+	for _, entities := range []any{&values, &pointers} {
+		if err := r.CallEach(context.Background(), OnAfterList, entities, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(values[0].Calls) != 1 || len(values[1].Calls) != 1 || len(pointers[0].Calls) != 1 {
+		t.Fatalf("expected one call per entity, got %v %v %v", values[0].Calls, values[1].Calls, pointers[0].Calls)
+	}
+}
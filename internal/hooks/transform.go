@@ -0,0 +1,94 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+)
+
+// Transformer converts a field value written by a client before it is saved,
+// e.g. hashing a password. It is selected with go-blar:"transform:<name>".
+type Transformer func(ctx context.Context, value any) (any, error)
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = make(map[string]Transformer)
+)
+
+// RegisterTransformer registers a transformer under name, replacing any previous one.
+func RegisterTransformer(name string, fn Transformer) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	transformers[name] = fn
+}
+
+// CallTransformers applies the transformers of the given fields, the fields
+// set by the request body, to entity, a pointer to a struct.
+func CallTransformers(ctx context.Context, entity any, fields []*meta.FieldMeta) error {
+	v := reflect.ValueOf(entity).Elem()
+
+	for _, f := range fields {
+		if f.Transform == "" {
+			continue
+		}
+
+		transformersMu.RLock()
+		fn, ok := transformers[f.Transform]
+		transformersMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown transformer %q for field %s", f.Transform, f.Name)
+		}
+
+		field := f.Settable(v)
+		value, err := fn(ctx, field.Interface())
+		if err != nil {
+			return err
+		}
+
+		rv := reflect.ValueOf(value)
+		if !rv.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if !convertible(rv.Type(), field.Type()) {
+			return fmt.Errorf("transformer %q returned %T for field %s of type %s", f.Transform, value, f.Name, field.Type())
+		}
+		field.Set(rv.Convert(field.Type()))
+	}
+
+	return nil
+}
+
+// convertible reports whether a transformed value of type from may be stored in
+// a field of type to: assignable values, numbers to numbers, and strings or
+// byte slices to strings or byte slices. Other conversions Go allows, such as
+// int to string, would silently change the value.
+func convertible(from, to reflect.Type) bool {
+	if from.AssignableTo(to) {
+		return true
+	}
+	if isNumber(from) && isNumber(to) {
+		return true
+	}
+	return isText(from) && isText(to)
+}
+
+// isNumber reports whether t is an integer or floating-point type.
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isText reports whether t is a string or byte slice type.
+func isText(t reflect.Type) bool {
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+)
+
+type Account struct {
+	ID       uint
+	Password string `go-blar:"writeonly;transform:upper"`
+	Note     string `go-blar:"transform:missing"`
+}
+
+func TestCallTransformers(t *testing.T) {
+	RegisterTransformer("upper", func(ctx context.Context, value any) (any, error) {
+		return strings.ToUpper(value.(string)), nil
+	})
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := &Account{Password: "secret"}
+	if err := CallTransformers(context.Background(), account, []*meta.FieldMeta{entityMeta.GetFieldByName("Password")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if account.Password != "SECRET" {
+		t.Fatalf("expected transformed password, got %q", account.Password)
+	}
+
+	// Fields not written by the client are left alone
+	if err := CallTransformers(context.Background(), account, nil); err != nil || account.Password != "SECRET" {
+		t.Fatalf("expected no transformation, got %q, %v", account.Password, err)
+	}
+
+	if err := CallTransformers(context.Background(), account, []*meta.FieldMeta{entityMeta.GetFieldByName("Note")}); err == nil {
+		t.Fatal("expected error for unknown transformer")
+	}
+}
+
+func TestCallTransformersError(t *testing.T) {
+	RegisterTransformer("fail", func(ctx context.Context, value any) (any, error) {
+		return nil, errors.New("transform failed")
+	})
+	RegisterTransformer("number", func(ctx context.Context, value any) (any, error) {
+		return 42, nil
+	})
+
+	type Entity struct {
+		ID    uint
+		Name  string   `go-blar:"transform:fail"`
+		Flags []string `go-blar:"transform:number"`
+		Code  string   `go-blar:"transform:number"`
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// int to string would store the rune 42, "*"
+	for _, name := range []string{"Name", "Flags", "Code"} {
+		if err := CallTransformers(context.Background(), &Entity{}, []*meta.FieldMeta{entityMeta.GetFieldByName(name)}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCallTransformersConversions(t *testing.T) {
+	RegisterTransformer("bytes", func(ctx context.Context, value any) (any, error) {
+		return []byte("hashed"), nil
+	})
+	RegisterTransformer("count", func(ctx context.Context, value any) (any, error) {
+		return 3, nil
+	})
+
+	type Hash string
+	type Entity struct {
+		ID       uint
+		Password Hash    `go-blar:"transform:bytes"`
+		Score    float64 `go-blar:"transform:count"`
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entity := &Entity{}
+	if err := CallTransformers(context.Background(), entity, entityMeta.Fields); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if entity.Password != "hashed" || entity.Score != 3 {
+		t.Fatalf("expected converted values, got %+v", entity)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+)
+
+// constraintKind is the kind of a violated database constraint.
+type constraintKind int
+
+const (
+	uniqueConstraint constraintKind = iota + 1
+	notNullConstraint
+	foreignKeyConstraint
+	checkConstraint
+)
+
+// constraint is a database constraint violation, with the columns or
+// constraint names involved when the driver reports them.
+type constraint struct {
+	kind  constraintKind
+	names []string
+}
+
+// constraintParser recognizes the constraint violations of one SQL dialect.
+type constraintParser func(err error) (constraint, bool)
+
+// constraintParsers are keyed by GORM dialector name. Postgres and MySQL report
+// SQLSTATE codes and error numbers and can be supported the same way.
+var constraintParsers = map[string]constraintParser{
+	"sqlite": parseSQLiteConstraint,
+}
+
+// parseConstraint recognizes a constraint violation, either translated by GORM
+// (gorm.Config.TranslateError) or reported by the driver of db's dialect.
+func parseConstraint(db *gorm.DB, err error) (constraint, bool) {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return constraint{kind: uniqueConstraint}, true
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return constraint{kind: foreignKeyConstraint}, true
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return constraint{kind: checkConstraint}, true
+	}
+
+	if parse, ok := constraintParsers[db.Dialector.Name()]; ok {
+		return parse(err)
+	}
+	return constraint{}, false
+}
+
+// sqliteConstraints are the SQLite error messages of each constraint kind,
+// e.g. "UNIQUE constraint failed: users.email".
+var sqliteConstraints = []struct {
+	message string
+	kind    constraintKind
+}{
+	{"UNIQUE constraint failed", uniqueConstraint},
+	{"NOT NULL constraint failed", notNullConstraint},
+	{"FOREIGN KEY constraint failed", foreignKeyConstraint},
+	{"CHECK constraint failed", checkConstraint},
+}
+
+// parseSQLiteConstraint parses a SQLite constraint error. Unique and not null
+// failures list table.column names; check failures name the constraint.
+func parseSQLiteConstraint(err error) (constraint, bool) {
+	msg := err.Error()
+	for _, sc := range sqliteConstraints {
+		i := strings.Index(msg, sc.message)
+		if i < 0 {
+			continue
+		}
+
+		c := constraint{kind: sc.kind}
+		if rest, ok := strings.CutPrefix(msg[i+len(sc.message):], ": "); ok {
+			for _, name := range strings.Split(rest, ", ") {
+				if j := strings.LastIndex(name, "."); j >= 0 {
+					name = name[j+1:]
+				}
+				c.names = append(c.names, name)
+			}
+		}
+		return c, true
+	}
+	return constraint{}, false
+}
+
+// constraintError converts a constraint violation from writing an entity into
+// a 409 for unique keys or a 422 for the others, naming the offending fields.
+// Other errors are returned unchanged.
+func (h *Handlers) constraintError(entityMeta *meta.EntityMeta, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	c, ok := parseConstraint(h.db, err)
+	if !ok {
+		return err
+	}
+
+	problem := &Error{Status: http.StatusUnprocessableEntity, Err: err}
+	var message string
+	switch c.kind {
+	case uniqueConstraint:
+		problem.Status = http.StatusConflict
+		problem.Detail, message = "entity already exists", "must be unique"
+	case notNullConstraint:
+		problem.Detail, message = "required field is missing", "is required"
+	case foreignKeyConstraint:
+		problem.Detail, message = "referenced entity does not exist", "references a missing entity"
+	case checkConstraint:
+		problem.Detail, message = "constraint violated", "is invalid"
+	}
+
+	for _, name := range c.names {
+		// GORM names check constraints chk_<table>_<column>
+		f := entityMeta.GetFieldByColumn(name)
+		if f == nil {
+			f = entityMeta.GetFieldByColumn(strings.TrimPrefix(name, "chk_"+entityMeta.TableName+"_"))
+		}
+		if f != nil && !f.Hidden {
+			problem.Fields = append(problem.Fields, FieldError{Field: f.JSONName, Message: message})
+		}
+	}
+	return problem
+}
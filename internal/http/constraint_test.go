@@ -0,0 +1,108 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Team struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type Member struct {
+	ID     uint    `gorm:"primaryKey"`
+	Email  string  `gorm:"unique" json:"email"`
+	Nick   *string `gorm:"not null" json:"nick"`
+	Age    int     `gorm:"check:age >= 0" json:"age"`
+	TeamID *uint   `json:"team_id"`
+	Team   *Team   `json:"-"`
+}
+
+// setupMembers serves Team and Member routes with foreign keys enforced.
+func setupMembers(t *testing.T) *Router {
+	db, err := gorm.Open(sqlite.Open(":memory:?_foreign_keys=on"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Team{}, &Member{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Team{Name: "red"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	router := New()
+	for _, model := range []any{&Team{}, &Member{}} {
+		entityMeta, err := meta.Parse(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+	}
+	return router
+}
+
+func TestParseSQLiteConstraint(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want constraint
+		ok   bool
+	}{
+		{"UNIQUE constraint failed: members.email", constraint{kind: uniqueConstraint, names: []string{"email"}}, true},
+		{"UNIQUE constraint failed: tags.a, tags.b", constraint{kind: uniqueConstraint, names: []string{"a", "b"}}, true},
+		{"NOT NULL constraint failed: members.nick", constraint{kind: notNullConstraint, names: []string{"nick"}}, true},
+		{"FOREIGN KEY constraint failed", constraint{kind: foreignKeyConstraint}, true},
+		{"CHECK constraint failed: chk_members_age", constraint{kind: checkConstraint, names: []string{"chk_members_age"}}, true},
+		{"no such table: members", constraint{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseSQLiteConstraint(errors.New(tt.msg))
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v %v, got %+v %v", tt.msg, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestConstraintViolations(t *testing.T) {
+	router := setupMembers(t)
+
+	if w := send(router, http.MethodPost, "/member", `{"email":"a@x.io","nick":"a","team_id":1}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		fields []FieldError
+	}{
+		{"unique", http.MethodPost, "/member", `{"email":"a@x.io","nick":"b"}`, http.StatusConflict, []FieldError{{Field: "email", Message: "must be unique"}}},
+		{"not null", http.MethodPost, "/member", `{"email":"b@x.io"}`, http.StatusUnprocessableEntity, []FieldError{{Field: "nick", Message: "is required"}}},
+		{"check", http.MethodPost, "/member", `{"email":"b@x.io","nick":"b","age":-1}`, http.StatusUnprocessableEntity, []FieldError{{Field: "age", Message: "is invalid"}}},
+		{"foreign key", http.MethodPost, "/member", `{"email":"b@x.io","nick":"b","team_id":9}`, http.StatusUnprocessableEntity, nil},
+		{"update", http.MethodPatch, "/member/1", `{"nick":null}`, http.StatusUnprocessableEntity, []FieldError{{Field: "nick", Message: "is required"}}},
+		{"referenced", http.MethodDelete, "/team/1", ``, http.StatusConflict, nil},
+	}
+
+	for _, tt := range tests {
+		w := send(router, tt.method, tt.target, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if p := decodeProblem(t, w); !reflect.DeepEqual(p.Errors, tt.fields) {
+			t.Errorf("%s: expected field errors %v, got %v", tt.name, tt.fields, p.Errors)
+		}
+	}
+}
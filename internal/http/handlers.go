@@ -1,26 +1,81 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/kamil5b/go-blar/internal/aggregate"
 	"github.com/kamil5b/go-blar/internal/hooks"
 	"github.com/kamil5b/go-blar/internal/meta"
+	"github.com/kamil5b/go-blar/internal/query"
+	"github.com/kamil5b/go-blar/internal/validate"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+// PaginationStyle selects how list pagination metadata is returned.
+type PaginationStyle int
+
+const (
+	// PaginationHeaders returns a bare JSON array with X-Total-Count and Link headers.
+	PaginationHeaders PaginationStyle = iota
+	// PaginationEnvelope wraps results as {"data": [...], "meta": {...}, "links": {...}}.
+	PaginationEnvelope
+)
+
+// ReadOnlyPolicy selects how writes to read-only fields are handled.
+type ReadOnlyPolicy int
+
+const (
+	// ReadOnlyIgnore silently drops read-only fields from request bodies.
+	ReadOnlyIgnore ReadOnlyPolicy = iota
+	// ReadOnlyReject rejects request bodies with read-only fields with 400 Bad Request.
+	ReadOnlyReject
+)
+
+// Options configures the generated handlers.
+type Options struct {
+	// DefaultPageSize is used when no size is requested; below 1 means 20.
+	// MaxPageSize caps requested sizes; 0 means no cap.
+	DefaultPageSize int
+	MaxPageSize     int
+	Pagination      PaginationStyle
+
+	// CursorPagination lists the entity types whose List endpoint uses
+	// keyset pagination with cursors signed by CursorSecret.
+	CursorPagination map[reflect.Type]bool
+	CursorSecret     []byte
+
+	// MaxIncludeDepth limits how many relations deep ?include= paths may go.
+	MaxIncludeDepth int
+
+	// ReadOnly selects how request bodies setting hidden, readonly or aggregate fields are handled.
+	ReadOnly ReadOnlyPolicy
+
+	// Hooks holds hooks registered in addition to the entities' hook methods.
+	Hooks *hooks.Registry
+}
+
 // Handlers provides HTTP handlers for entity CRUD operations.
 // It is used internally and should not be exposed in the public API.
 type Handlers struct {
-	db *gorm.DB
+	db   *gorm.DB
+	opts Options
 }
 
 // NewHandlers creates a new Handlers instance.
-func NewHandlers(db *gorm.DB) *Handlers {
-	return &Handlers{db: db}
+func NewHandlers(db *gorm.DB, opts Options) *Handlers {
+	return &Handlers{db: db, opts: opts}
 }
 
 // CreateHandler returns an HTTP handler for creating a new entity.
@@ -30,165 +85,668 @@ func (h *Handlers) CreateHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
 
 		// Decode JSON body
 		entity := makeEntityInstance(entityMeta)
-		if err := json.NewDecoder(r.Body).Decode(entity); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		written, err := decode(r.Body, entityMeta, entity, h.opts.ReadOnly)
+		if err != nil {
+			writeError(w, r, decodeError(err))
 			return
 		}
 
-		// Call BeforeCreate hook
-		if err := hooks.CallBeforeCreate(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+		// Create in one transaction with the hooks, so a failing hook rolls it back
+		err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Validate before values are transformed
+			if err := validate.Entity(ctx, tx, validate.Create, entityMeta, entity, written); err != nil {
+				return err
+			}
+
+			// Transform written values, e.g. hash passwords
+			if err := hooks.CallTransformers(ctx, entity, written); err != nil {
+				return err
+			}
+
+			// Call BeforeCreate hooks
+			if err := h.opts.Hooks.Call(ctx, hooks.OnBeforeCreate, entity, tx); err != nil {
+				return err
+			}
+
+			// Save to database
+			if err := tx.Omit(clause.Associations).Create(entity).Error; err != nil {
+				return err
+			}
 
-		// Save to database
-		if err := h.db.WithContext(ctx).Create(entity).Error; err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			// Call AfterCreate hooks
+			return h.opts.Hooks.Call(ctx, hooks.OnAfterCreate, entity, tx)
+		})
+		if err != nil {
+			writeError(w, r, h.constraintError(entityMeta, err))
 			return
 		}
 
-		// Call AfterCreate hook
-		if err := hooks.CallAfterCreate(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Compute aggregate fields for the response
+		if err := aggregate.ComputeAggregates(h.db.WithContext(ctx), entity, entityMeta.Aggregates); err != nil {
+			writeError(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(entity)
+		json.NewEncoder(w).Encode(serialize(entityMeta, entity, nil))
 	}
 }
 
-// ListHandler returns an HTTP handler for listing all entities.
+// ListHandler returns an HTTP handler for listing entities.
+// It supports filtering, sorting and offset pagination through query parameters.
 func (h *Handlers) ListHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
+		params := r.URL.Query()
+
+		// Parse query-string filters, sorting and pagination
+		filters, err := query.ParseFilters(params, entityMeta)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		sorts, err := query.ParseSort(params, entityMeta)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		fields, err := query.ParseFields(params, entityMeta)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		includes, err := query.ParseIncludes(params, entityMeta, h.opts.MaxIncludeDepth)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		// Let the entity type scope the query, e.g. by tenant
+		db, err := hooks.CallBeforeList(ctx, makeEntityInstance(entityMeta), h.db.WithContext(ctx))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		db = query.ApplyFilters(db, filters).Session(&gorm.Session{})
+
+		if h.opts.CursorPagination[entityMeta.Type] {
+			h.listByCursor(w, r, db, entityMeta, sorts, fields, includes)
+			return
+		}
+
+		page, err := query.ParsePage(params, h.opts.DefaultPageSize, h.opts.MaxPageSize)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		// Count all matching rows
+		var total int64
+		if err := db.Model(makeEntityInstance(entityMeta)).Count(&total).Error; err != nil {
+			writeError(w, r, err)
+			return
+		}
 
 		// Create a slice of the entity type
 		entities := makeEntitySlice(entityMeta)
 
 		// Query database
-		if err := h.db.WithContext(ctx).Find(entities).Error; err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		find := query.ApplyIncludes(query.ApplyFields(db, fields, query.IncludeKeys(includes)...), includes)
+		if err := page.Apply(query.ApplySort(find, sorts)).Find(entities).Error; err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(entities)
+		// Compute aggregate fields
+		if err := aggregate.ComputeAggregates(h.db.WithContext(ctx), entities, query.Aggregates(entityMeta, fields)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// Call AfterList hooks on each entity
+		if err := h.opts.Hooks.CallEach(ctx, hooks.OnAfterList, entities, h.db.WithContext(ctx)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		h.writeList(w, r, serialize(entityMeta, entities, query.IncludeFields(fields, includes)), page, total)
 	}
 }
 
-// GetHandler returns an HTTP handler for retrieving a single entity by ID.
-func (h *Handlers) GetHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+// listByCursor serves a List request with keyset pagination, starting from the
+// scoped and filtered query db. It fetches one extra row to find out whether
+// there is a next page.
+func (h *Handlers) listByCursor(w http.ResponseWriter, r *http.Request, db *gorm.DB, entityMeta *meta.EntityMeta, sorts []query.Sort, fields []*meta.FieldMeta, includes []query.Include) {
+	ctx := r.Context()
+
+	page, err := query.ParseCursorPage(r.URL.Query(), h.opts.DefaultPageSize, h.opts.MaxPageSize)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
 
-		// Extract ID from URL
-		idStr := chi.URLParam(r, "id")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+	// Sort columns are selected too, since the next cursor is built from them
+	extra := query.IncludeKeys(includes)
+	for _, s := range sorts {
+		extra = append(extra, s.Field)
+	}
+
+	db = query.ApplySort(query.ApplyIncludes(query.ApplyFields(db, fields, extra...), includes), sorts)
+	if page.Cursor != "" {
+		values, err := query.DecodeCursor(h.opts.CursorSecret, page.Cursor, sorts)
 		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			writeError(w, r, badRequest(err))
 			return
 		}
+		db = query.ApplyCursor(db, sorts, values)
+	}
 
-		// Query database
-		entity := makeEntityInstance(entityMeta)
-		if err := h.db.WithContext(ctx).First(entity, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				http.Error(w, "Not found", http.StatusNotFound)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+	// Query database
+	entities := makeEntitySlice(entityMeta)
+	if err := db.Limit(page.Size + 1).Find(entities).Error; err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	nextCursor := ""
+	rows := reflect.ValueOf(entities).Elem()
+	if rows.Len() > page.Size {
+		rows.SetLen(page.Size)
+		nextCursor, err = query.EncodeCursor(h.opts.CursorSecret, sorts, rows.Index(page.Size-1))
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(entity)
+	// Compute aggregate fields
+	if err := aggregate.ComputeAggregates(h.db.WithContext(ctx), entities, query.Aggregates(entityMeta, fields)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Call AfterList hooks on each entity
+	if err := h.opts.Hooks.CallEach(ctx, hooks.OnAfterList, entities, h.db.WithContext(ctx)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	body := serialize(entityMeta, entities, query.IncludeFields(fields, includes))
+	links := make(map[string]string)
+	if nextCursor != "" {
+		links["next"] = page.CursorLink(requestURL(r), nextCursor)
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.opts.Pagination == PaginationEnvelope {
+		json.NewEncoder(w).Encode(listEnvelope{
+			Data:  body,
+			Meta:  cursorMeta{Limit: page.Size, NextCursor: nextCursor},
+			Links: links,
+		})
+		return
+	}
+
+	if nextCursor != "" {
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=%q", links["next"], "next"))
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	json.NewEncoder(w).Encode(body)
 }
 
-// UpdateHandler returns an HTTP handler for updating an entity.
-func (h *Handlers) UpdateHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
+// listEnvelope is the list response body for PaginationEnvelope.
+type listEnvelope struct {
+	Data  any               `json:"data"`
+	Meta  any               `json:"meta"`
+	Links map[string]string `json:"links"`
+}
+
+// listMeta holds the pagination metadata of an offset-paginated list response.
+type listMeta struct {
+	Total   int64 `json:"total"`
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+}
+
+// cursorMeta holds the pagination metadata of a cursor-paginated list response.
+type cursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// writeList writes a page of entities with its pagination metadata.
+func (h *Handlers) writeList(w http.ResponseWriter, r *http.Request, entities any, page query.Page, total int64) {
+	links := page.Links(requestURL(r), total)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.opts.Pagination == PaginationEnvelope {
+		json.NewEncoder(w).Encode(listEnvelope{
+			Data:  entities,
+			Meta:  listMeta{Total: total, Page: page.Number, PerPage: page.Size},
+			Links: links,
+		})
+		return
+	}
+
+	var header []string
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if link, ok := links[rel]; ok {
+			header = append(header, fmt.Sprintf("<%s>; rel=%q", link, rel))
+		}
+	}
+	w.Header().Set("Link", strings.Join(header, ", "))
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	json.NewEncoder(w).Encode(entities)
+}
+
+// requestURL returns the URL as the client sent it, so links stay correct
+// when the app is mounted under a stripped prefix.
+func requestURL(r *http.Request) *url.URL {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u
+	}
+	return r.URL
+}
+
+// GetHandler returns an HTTP handler for retrieving a single entity by ID.
+func (h *Handlers) GetHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// Extract ID from URL
-		idStr := chi.URLParam(r, "id")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+		// Extract primary key from URL
+		key, err := query.ParseKey(entityMeta, keyParam(r))
 		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			writeError(w, r, newError(http.StatusBadRequest, "invalid ID: "+err.Error()))
 			return
 		}
 
-		// Decode JSON body
+		// Parse sparse fieldset and included relations
+		fields, err := query.ParseFields(r.URL.Query(), entityMeta)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		includes, err := query.ParseIncludes(r.URL.Query(), entityMeta, h.opts.MaxIncludeDepth)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		// Let the entity type scope the query, e.g. by tenant
 		entity := makeEntityInstance(entityMeta)
-		if err := json.NewDecoder(r.Body).Decode(entity); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		db, err := hooks.CallBeforeGet(ctx, entity, h.db.WithContext(ctx))
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		// Call BeforeUpdate hook
-		if err := hooks.CallBeforeUpdate(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Query database
+		db = query.ApplyFields(db, fields, query.IncludeKeys(includes)...)
+		if err := query.ApplyKey(query.ApplyIncludes(db, includes), entityMeta, key).First(entity).Error; err != nil {
+			writeError(w, r, findError(err))
 			return
 		}
 
-		// Update in database
-		if err := h.db.WithContext(ctx).Model(entity).Where("id = ?", id).Updates(entity).Error; err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Compute aggregate fields
+		if err := aggregate.ComputeAggregates(h.db.WithContext(ctx), entity, query.Aggregates(entityMeta, fields)); err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		// Call AfterUpdate hook
-		if err := hooks.CallAfterUpdate(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Call AfterGet hooks
+		if err := h.opts.Hooks.Call(ctx, hooks.OnAfterGet, entity, h.db.WithContext(ctx)); err != nil {
+			writeError(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(entity)
+		json.NewEncoder(w).Encode(serialize(entityMeta, entity, query.IncludeFields(fields, includes)))
 	}
 }
 
-// DeleteHandler returns an HTTP handler for deleting an entity.
-func (h *Handlers) DeleteHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
+// UpdateHandler returns an HTTP handler that replaces an entity (PUT).
+// Writable fields missing from the body are reset to their zero values, while
+// read-only fields, GORM timestamps and omitted write-only fields are kept.
+func (h *Handlers) UpdateHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		h.update(w, r, entityMeta, func(entity any, sch *schema.Schema) (changes, error) {
+			// Decode JSON body and replace the stored fields
+			replacement := makeEntityInstance(entityMeta)
+			written, err := decode(r.Body, entityMeta, replacement, h.opts.ReadOnly)
+			if err != nil {
+				return changes{}, decodeError(err)
+			}
+			replace(sch, entityMeta, entity, replacement, written)
+			return changes{written: written}, nil
+		})
+	}
+}
 
-		// Extract ID from URL
-		idStr := chi.URLParam(r, "id")
-		id, err := strconv.ParseInt(idStr, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+// PatchHandler returns an HTTP handler that patches an entity (PATCH) with a
+// JSON merge patch (RFC 7396), or with a JSON Patch (RFC 6902) when the
+// Content-Type is application/json-patch+json. Only the patched fields change.
+func (h *Handlers) PatchHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == jsonPatchType {
+			h.jsonPatch(w, r, entityMeta)
 			return
 		}
 
-		// Fetch entity first (for hooks)
-		entity := makeEntityInstance(entityMeta)
-		if err := h.db.WithContext(ctx).First(entity, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				http.Error(w, "Not found", http.StatusNotFound)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.update(w, r, entityMeta, func(entity any, sch *schema.Schema) (changes, error) {
+			patched, err := mergePatch(r.Body, sch, entityMeta, entity, h.opts.ReadOnly)
+			if err != nil {
+				return changes{}, decodeError(err)
 			}
-			return
+			return changes{written: patched}, nil
+		})
+	}
+}
+
+// jsonPatch serves a PATCH request with a JSON Patch document. The operations
+// are applied in memory first, so a failed test (409 Conflict) or invalid
+// operation (400) leaves the entity untouched.
+func (h *Handlers) jsonPatch(w http.ResponseWriter, r *http.Request, entityMeta *meta.EntityMeta) {
+	patch, err := parseJSONPatch(r.Body, entityMeta)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	h.update(w, r, entityMeta, func(entity any, sch *schema.Schema) (changes, error) {
+		ch, err := patch.apply(sch, entity, h.opts.ReadOnly)
+		if errors.Is(err, errPatchTest) {
+			return changes{}, &Error{Status: http.StatusConflict, Detail: err.Error(), Err: err}
 		}
+		if err != nil {
+			return changes{}, badRequest(err)
+		}
+		return ch, nil
+	}, patch.relations()...)
+}
 
-		// Call BeforeDelete hook
-		if err := hooks.CallBeforeDelete(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+// changes describes what a modifier changed in a loaded entity.
+type changes struct {
+	// written lists the column fields set.
+	written []*meta.FieldMeta
+
+	// collections lists the list/m2m collections changed.
+	collections []*meta.RelationMeta
+
+	// added lists the elements added to the collections as new rows.
+	added []addedElement
+}
+
+// addedElement is a collection element without a primary key, which is
+// created like an entity of the related type before the collection is saved.
+type addedElement struct {
+	relation *meta.RelationMeta
+	index    int
+	written  []*meta.FieldMeta
+}
+
+// modifier changes a loaded entity for an update and returns its changes.
+type modifier func(entity any, sch *schema.Schema) (changes, error)
+
+// update serves PUT and PATCH. In one transaction it loads the entity addressed
+// by the request with its row locked, changes it with modify, validates it and
+// transforms the written fields, and saves it with all its columns and the
+// changed collections, running the update hooks. Elements added to collections
+// are validated, transformed and created with the create hooks of their own
+// entity type first. Concurrent writes therefore cannot slip in between reading
+// the entity and saving it.
+func (h *Handlers) update(w http.ResponseWriter, r *http.Request, entityMeta *meta.EntityMeta, modify modifier, relations ...*meta.RelationMeta) {
+	ctx := r.Context()
+
+	// Extract primary key from URL
+	key, err := query.ParseKey(entityMeta, keyParam(r))
+	if err != nil {
+		writeError(w, r, newError(http.StatusBadRequest, "invalid ID: "+err.Error()))
+		return
+	}
+
+	var entity any
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		loaded, sch, err := load(ctx, tx, entityMeta, key, relations...)
+		if err != nil {
+			return err
+		}
+		entity = loaded
+
+		ch, err := modify(entity, sch)
+		if err != nil {
+			return err
+		}
+
+		// Validate before values are transformed
+		if err := validateChanges(ctx, tx, sch, entityMeta, entity, ch); err != nil {
+			return err
+		}
+
+		// Transform written values, e.g. hash passwords
+		if err := hooks.CallTransformers(ctx, entity, ch.written); err != nil {
+			return err
+		}
+
+		// Call BeforeUpdate hooks
+		if err := h.opts.Hooks.Call(ctx, hooks.OnBeforeUpdate, entity, tx); err != nil {
+			return err
+		}
+
+		// Update in database, including zero values
+		db := query.ApplyKey(tx.Model(entity), entityMeta, query.KeyOf(entityMeta, entity))
+		if err := db.Select("*").Omit(clause.Associations).Updates(entity).Error; err != nil {
+			return err
+		}
+
+		// Create added elements, then replace changed list/m2m collections
+		if err := h.createAdded(ctx, tx, entity, ch.added); err != nil {
+			return err
+		}
+		v := reflect.ValueOf(entity).Elem()
+		for _, rel := range ch.collections {
+			items := rel.Field.Value(v).Interface()
+			if err := tx.Model(entity).Association(rel.Name).Replace(items); err != nil {
+				return err
+			}
+		}
+
+		// Call AfterUpdate hooks
+		return h.opts.Hooks.Call(ctx, hooks.OnAfterUpdate, entity, tx)
+	})
+	if err != nil {
+		writeError(w, r, h.constraintError(entityMeta, err))
+		return
+	}
+
+	// Compute aggregate fields for the response
+	if err := aggregate.ComputeAggregates(h.db.WithContext(ctx), entity, entityMeta.Aggregates); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serialize(entityMeta, entity, nil))
+}
+
+// validateChanges validates entity for an update and the elements added to its
+// collections as new rows of their entity type. Invalid element fields are
+// reported by their path from the entity, e.g. "items/0/name". The elements of
+// list collections get their foreign key first, so rules on it pass.
+func validateChanges(ctx context.Context, tx *gorm.DB, sch *schema.Schema, entityMeta *meta.EntityMeta, entity any, ch changes) error {
+	var errs validate.Errors
+	collect := func(err error, prefix string) error {
+		var fieldErrs validate.Errors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		for _, fe := range fieldErrs {
+			errs = append(errs, validate.FieldError{Field: prefix + fe.Field, Message: fe.Message})
+		}
+		return nil
+	}
+
+	if err := validate.Entity(ctx, tx, validate.Update, entityMeta, entity, ch.written); err != nil {
+		if err := collect(err, ""); err != nil {
+			return err
+		}
+	}
+
+	v := reflect.ValueOf(entity).Elem()
+	for _, a := range ch.added {
+		elem := elementAt(a.relation.Field.Value(v), a.index)
+		if err := setOwner(ctx, sch, a.relation, entity, elem); err != nil {
+			return err
+		}
+		err := validate.Entity(ctx, tx, validate.Create, a.relation.Entity, elem, a.written)
+		if err != nil {
+			if err := collect(err, fmt.Sprintf("%s/%d/", a.relation.Field.JSONName, a.index)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// setOwner sets the foreign key of elem, an element of a list collection of
+// entity, to entity's key. Many-to-many elements are linked through the join
+// table instead.
+func setOwner(ctx context.Context, sch *schema.Schema, rel *meta.RelationMeta, entity, elem any) error {
+	relationship := sch.Relationships.Relations[rel.Name]
+	if relationship == nil || relationship.Type != schema.HasMany {
+		return nil
+	}
+
+	owner := reflect.ValueOf(entity).Elem()
+	target := reflect.ValueOf(elem).Elem()
+	for _, ref := range relationship.References {
+		var err error
+		if ref.OwnPrimaryKey {
+			err = ref.ForeignKey.Set(ctx, target, ref.PrimaryKey.ReflectValueOf(ctx, owner).Interface())
+		} else if ref.PrimaryValue != "" {
+			err = ref.ForeignKey.Set(ctx, target, ref.PrimaryValue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createAdded creates the elements added to entity's collections within tx,
+// transforming their written fields and running the create hooks of their
+// entity type, as CreateHandler does.
+func (h *Handlers) createAdded(ctx context.Context, tx *gorm.DB, entity any, added []addedElement) error {
+	v := reflect.ValueOf(entity).Elem()
+	for _, a := range added {
+		elem := elementAt(a.relation.Field.Value(v), a.index)
+
+		if err := hooks.CallTransformers(ctx, elem, a.written); err != nil {
+			return err
+		}
+		if err := h.opts.Hooks.Call(ctx, hooks.OnBeforeCreate, elem, tx); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(elem).Error; err != nil {
+			return err
 		}
+		if err := h.opts.Hooks.Call(ctx, hooks.OnAfterCreate, elem, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// Delete from database
-		if err := h.db.WithContext(ctx).Delete(entity, id).Error; err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+// load fetches the entity with the given key for an update within tx, with its
+// row locked where the dialect supports it, scoped by the BeforeGet hook, and
+// with the given collections preloaded in primary key order. It returns the
+// entity together with its GORM schema.
+func load(ctx context.Context, tx *gorm.DB, entityMeta *meta.EntityMeta, key []any, relations ...*meta.RelationMeta) (any, *schema.Schema, error) {
+	// Rows the entity type hides from Get cannot be written either
+	entity := makeEntityInstance(entityMeta)
+	db, err := hooks.CallBeforeGet(ctx, entity, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, rel := range relations {
+		db = db.Preload(rel.Name, orderByPK(rel.Entity))
+	}
+
+	db = db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate})
+	if err := query.ApplyKey(db, entityMeta, key).First(entity).Error; err != nil {
+		return nil, nil, findError(err)
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(entity); err != nil {
+		return nil, nil, err
+	}
+
+	return entity, stmt.Schema, nil
+}
+
+// orderByPK returns a preload condition ordering related rows by primary key.
+func orderByPK(entityMeta *meta.EntityMeta) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, pk := range entityMeta.PKFields {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.Column}})
+		}
+		return db
+	}
+}
+
+// DeleteHandler returns an HTTP handler for deleting an entity.
+func (h *Handlers) DeleteHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		// Extract primary key from URL
+		key, err := query.ParseKey(entityMeta, keyParam(r))
+		if err != nil {
+			writeError(w, r, newError(http.StatusBadRequest, "invalid ID: "+err.Error()))
 			return
 		}
 
-		// Call AfterDelete hook
-		if err := hooks.CallAfterDelete(ctx, entity, h.db); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Delete in one transaction with the hooks, so a failing hook rolls it back
+		err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Fetch entity first (for hooks), scoped like Get
+			entity := makeEntityInstance(entityMeta)
+			db, err := hooks.CallBeforeGet(ctx, entity, tx)
+			if err != nil {
+				return err
+			}
+			if err := query.ApplyKey(db, entityMeta, key).First(entity).Error; err != nil {
+				return findError(err)
+			}
+
+			// Call BeforeDelete hooks
+			if err := h.opts.Hooks.Call(ctx, hooks.OnBeforeDelete, entity, tx); err != nil {
+				return err
+			}
+
+			// Delete from database
+			if err := query.ApplyKey(tx, entityMeta, key).Delete(entity).Error; err != nil {
+				if c, ok := parseConstraint(tx, err); ok && c.kind == foreignKeyConstraint {
+					return &Error{Status: http.StatusConflict, Detail: "entity is still referenced", Err: err}
+				}
+				return err
+			}
+
+			// Call AfterDelete hooks
+			return h.opts.Hooks.Call(ctx, hooks.OnAfterDelete, entity, tx)
+		})
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
@@ -196,6 +754,38 @@ func (h *Handlers) DeleteHandler(entityMeta *meta.EntityMeta) http.HandlerFunc {
 	}
 }
 
+// decodeError converts a request body decoding error into a 400 problem.
+func decodeError(err error) *Error {
+	var fieldErr fieldError
+	if errors.As(err, &fieldErr) {
+		return &Error{
+			Status: http.StatusBadRequest,
+			Detail: "invalid request body",
+			Fields: []FieldError{{Field: fieldErr.field, Message: fieldErr.reason}},
+		}
+	}
+	return newError(http.StatusBadRequest, "invalid request body: "+err.Error())
+}
+
+// findError converts gorm.ErrRecordNotFound into a 404 problem.
+func findError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Status: http.StatusNotFound, Detail: "entity not found", Err: err}
+	}
+	return err
+}
+
+// keyParam returns the {id} URL parameter in escaped form. chi matches on the
+// decoded path unless the request escaped more than the default, e.g. %2C.
+func keyParam(r *http.Request) string {
+	id := chi.URLParam(r, "id")
+	if r.URL.RawPath != "" {
+		return id
+	}
+	// Commas in the decoded path were literal key separators
+	return strings.ReplaceAll(url.PathEscape(id), "%2C", ",")
+}
+
 // makeEntityInstance creates a new instance of the entity type.
 func makeEntityInstance(entityMeta *meta.EntityMeta) any {
 	return reflect.New(entityMeta.Type).Interface()
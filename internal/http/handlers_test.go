@@ -0,0 +1,1411 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamil5b/go-blar/internal/hooks"
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Widget struct {
+	ID     uint `gorm:"primaryKey"`
+	Name   string
+	Price  float64
+	Secret string `go-blar:"hidden"`
+	Code   string `go-blar:"readonly"`
+}
+
+// setupRouter creates a router serving Widget routes backed by an in-memory database.
+func setupRouter(t *testing.T, opts Options, widgets ...Widget) (*Router, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Widget{}); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+
+	if len(widgets) > 0 {
+		if err := db.Create(&widgets).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Widget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, opts))
+	return router, db
+}
+
+// serve sends a request to the router and returns the recorded response.
+func serve(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func testWidgets() []Widget {
+	return []Widget{
+		{Name: "a", Price: 5},
+		{Name: "b", Price: 15},
+		{Name: "c", Price: 25},
+		{Name: "d", Price: 35},
+		{Name: "e", Price: 45},
+	}
+}
+
+func TestListHandlerPaginationHeaders(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 2, MaxPageSize: 10}, testWidgets()...)
+
+	w := serve(router, http.MethodGet, "/widget?price[gt]=10&sort=-price&page=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if got := w.Header().Get("X-Total-Count"); got != "4" {
+		t.Fatalf("expected X-Total-Count 4, got %s", got)
+	}
+
+	expectedLink := `</widget?page=1&per_page=2&price%5Bgt%5D=10&sort=-price>; rel="first", ` +
+		`</widget?page=1&per_page=2&price%5Bgt%5D=10&sort=-price>; rel="prev", ` +
+		`</widget?page=2&per_page=2&price%5Bgt%5D=10&sort=-price>; rel="last"`
+	if got := w.Header().Get("Link"); got != expectedLink {
+		t.Fatalf("unexpected Link header:\n got %s\nwant %s", got, expectedLink)
+	}
+
+	var widgets []Widget
+	if err := json.Unmarshal(w.Body.Bytes(), &widgets); err != nil {
+		t.Fatal(err)
+	}
+	if len(widgets) != 2 || widgets[0].Name != "c" || widgets[1].Name != "b" {
+		t.Fatalf("unexpected page: %+v", widgets)
+	}
+}
+
+func TestListHandlerPaginationEnvelope(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 20, MaxPageSize: 2, Pagination: PaginationEnvelope}, testWidgets()...)
+
+	w := serve(router, http.MethodGet, "/widget?per_page=50")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data  []Widget          `json:"data"`
+		Meta  listMeta          `json:"meta"`
+		Links map[string]string `json:"links"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Data) != 2 {
+		t.Fatalf("expected page size capped at 2, got %d", len(body.Data))
+	}
+	if body.Meta.Total != 5 || body.Meta.Page != 1 || body.Meta.PerPage != 2 {
+		t.Fatalf("unexpected meta: %+v", body.Meta)
+	}
+	if body.Links["next"] != "/widget?page=2&per_page=2" {
+		t.Fatalf("unexpected next link: %s", body.Links["next"])
+	}
+}
+
+func TestListHandlerBadQuery(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 20})
+
+	for _, target := range []string{"/widget?unknown=1", "/widget?sort=unknown", "/widget?page=0"} {
+		if w := serve(router, http.MethodGet, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestListHandlerCursorPagination(t *testing.T) {
+	opts := Options{
+		DefaultPageSize:  2,
+		MaxPageSize:      10,
+		CursorPagination: map[reflect.Type]bool{reflect.TypeOf(Widget{}): true},
+		CursorSecret:     []byte("secret"),
+	}
+	router, _ := setupRouter(t, opts, testWidgets()...)
+
+	var names []string
+	target := "/widget?sort=-price"
+	for pages := 0; target != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("too many pages")
+		}
+
+		w := serve(router, http.MethodGet, target)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var widgets []Widget
+		if err := json.Unmarshal(w.Body.Bytes(), &widgets); err != nil {
+			t.Fatal(err)
+		}
+		for _, widget := range widgets {
+			names = append(names, widget.Name)
+		}
+
+		target = ""
+		if cursor := w.Header().Get("X-Next-Cursor"); cursor != "" {
+			target = "/widget?sort=-price&limit=2&cursor=" + url.QueryEscape(cursor)
+		}
+	}
+
+	if strings.Join(names, "") != "edcba" {
+		t.Fatalf("expected all widgets in descending price order, got %v", names)
+	}
+
+	if w := serve(router, http.MethodGet, "/widget?cursor=bogus"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for invalid cursor, got %d", w.Code)
+	}
+}
+
+func TestListHandlerCursorEnvelope(t *testing.T) {
+	opts := Options{
+		DefaultPageSize:  3,
+		Pagination:       PaginationEnvelope,
+		CursorPagination: map[reflect.Type]bool{reflect.TypeOf(Widget{}): true},
+		CursorSecret:     []byte("secret"),
+	}
+	router, _ := setupRouter(t, opts, testWidgets()...)
+
+	w := serve(router, http.MethodGet, "/widget")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data  []Widget          `json:"data"`
+		Meta  cursorMeta        `json:"meta"`
+		Links map[string]string `json:"links"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Data) != 3 || body.Meta.Limit != 3 || body.Meta.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", body)
+	}
+
+	next := body.Links["next"]
+	body.Meta, body.Links = cursorMeta{}, nil
+	w = serve(router, http.MethodGet, next)
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Data) != 2 || body.Meta.NextCursor != "" || body.Links["next"] != "" {
+		t.Fatalf("unexpected last page: %+v", body)
+	}
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 20}, testWidgets()...)
+
+	w := serve(router, http.MethodGet, "/widget/2?fields=name")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"ID":2,"Name":"b"}` {
+		t.Fatalf("unexpected Get body: %s", got)
+	}
+
+	w = serve(router, http.MethodGet, "/widget?fields=price&sort=price&per_page=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `[{"ID":1,"Price":5},{"ID":2,"Price":15}]` {
+		t.Fatalf("unexpected List body: %s", got)
+	}
+
+	for _, target := range []string{"/widget/1?fields=secret", "/widget?fields=unknown"} {
+		if w := serve(router, http.MethodGet, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
+
+type Maker struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type Gizmo struct {
+	ID      uint `gorm:"primaryKey"`
+	Name    string
+	MakerID uint   `go-blar:"fk:Maker"`
+	Maker   *Maker `gorm:"foreignKey:MakerID"`
+}
+
+func TestIncludeRelations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Maker{}, &Gizmo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Gizmo{Name: "g", Maker: &Maker{Name: "acme"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Gizmo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{DefaultPageSize: 20, MaxIncludeDepth: 1}))
+
+	w := serve(router, http.MethodGet, "/gizmo/1?include=maker&fields=name")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"ID":1,"Name":"g","Maker":{"ID":1,"Name":"acme"}}` {
+		t.Fatalf("unexpected Get body: %s", got)
+	}
+
+	w = serve(router, http.MethodGet, "/gizmo?include=maker")
+	var gizmos []Gizmo
+	if err := json.Unmarshal(w.Body.Bytes(), &gizmos); err != nil {
+		t.Fatal(err)
+	}
+	if len(gizmos) != 1 || gizmos[0].Maker == nil || gizmos[0].Maker.Name != "acme" {
+		t.Fatalf("expected maker to be included, got %s", w.Body.String())
+	}
+
+	w = serve(router, http.MethodGet, "/gizmo")
+	if strings.Contains(w.Body.String(), "acme") {
+		t.Fatalf("expected maker not to be included by default, got %s", w.Body.String())
+	}
+
+	for _, target := range []string{"/gizmo?include=owner", "/gizmo/1?include=maker.gizmos"} {
+		if w := serve(router, http.MethodGet, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHiddenAndReadOnlyFields(t *testing.T) {
+	router, db := setupRouter(t, Options{DefaultPageSize: 20}, Widget{Name: "a", Secret: "s3cret", Code: "A1"})
+
+	for _, target := range []string{"/widget/1", "/widget"} {
+		w := serve(router, http.MethodGet, target)
+		if strings.Contains(w.Body.String(), "Secret") || strings.Contains(w.Body.String(), "s3cret") {
+			t.Fatalf("%s: expected hidden field to be stripped, got %s", target, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"Code":"A1"`) {
+			t.Fatalf("%s: expected readonly field to be returned, got %s", target, w.Body.String())
+		}
+	}
+
+	body := `{"Name":"b","Price":2,"Secret":"x","Code":"B2"}`
+	req := httptest.NewRequest(http.MethodPost, "/widget", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"ID":2,"Name":"b","Price":2,"Code":""}` {
+		t.Fatalf("unexpected Create body: %s", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/widget/1", strings.NewReader(`{"Name":"c","Code":"C3"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var widgets []Widget
+	if err := db.Order("id").Find(&widgets).Error; err != nil {
+		t.Fatal(err)
+	}
+	if widgets[0].Name != "c" || widgets[0].Code != "A1" || widgets[0].Secret != "s3cret" {
+		t.Fatalf("expected readonly and hidden fields to be kept on update, got %+v", widgets[0])
+	}
+	if widgets[1].Secret != "" || widgets[1].Code != "" {
+		t.Fatalf("expected readonly and hidden fields to be ignored on create, got %+v", widgets[1])
+	}
+}
+
+func TestReadOnlyReject(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 20, ReadOnly: ReadOnlyReject})
+
+	for _, body := range []string{`{"Name":"b","code":"B2"}`, `{"Name":"b","Secret":"x"}`, `[]`, `null`} {
+		req := httptest.NewRequest(http.MethodPost, "/widget", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/widget", strings.NewReader(`{"Name":"b"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+type Account struct {
+	ID       uint `gorm:"primaryKey"`
+	Email    string
+	Password string `go-blar:"writeonly;transform:reverse;validate:required,min=6"`
+}
+
+func TestWriteOnlyFields(t *testing.T) {
+	hooks.RegisterTransformer("reverse", func(ctx context.Context, value any) (any, error) {
+		r := []rune(value.(string))
+		slices.Reverse(r)
+		return string(r), nil
+	})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Account{}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{DefaultPageSize: 20}))
+
+	// Create checks write-only fields the body left out
+	if w := send(router, http.MethodPost, "/account", `{"Email":"a@b.c"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422 without a password, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/account", strings.NewReader(`{"Email":"a@b.c","Password":"secret"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "Password") {
+		t.Fatalf("expected password not to be returned, got %s", w.Body.String())
+	}
+
+	// Updates without the field keep the stored value
+	req = httptest.NewRequest(http.MethodPut, "/account/1", strings.NewReader(`{"Email":"x@b.c"}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var account Account
+	if err := db.First(&account, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if account.Password != "terces" || account.Email != "x@b.c" {
+		t.Fatalf("expected transformed password to be stored, got %+v", account)
+	}
+
+	for _, target := range []string{"/account/1", "/account"} {
+		if w := serve(router, http.MethodGet, target); strings.Contains(w.Body.String(), "Password") {
+			t.Fatalf("%s: expected password not to be returned, got %s", target, w.Body.String())
+		}
+	}
+	for _, target := range []string{"/account?password=terces", "/account?sort=password", "/account/1?fields=password"} {
+		if w := serve(router, http.MethodGet, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
+
+type Gadget struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Stock     int
+	Active    bool
+	Note      *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BeforeUpdate sees the loaded, merged entity, so a patch without Name passes.
+func (g *Gadget) BeforeUpdate(ctx context.Context, tx *gorm.DB) error {
+	if g.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// setupGadgets serves Gadget routes with one stored gadget.
+func setupGadgets(t *testing.T, opts Options) (*Router, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Gadget{}); err != nil {
+		t.Fatal(err)
+	}
+	note := "fragile"
+	if err := db.Create(&Gadget{Name: "g", Stock: 5, Active: true, Note: &note}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Gadget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, opts))
+	return router, db
+}
+
+// send sends a request with a body to the router and returns the recorded response.
+func send(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatchMergesFields(t *testing.T) {
+	router, db := setupGadgets(t, Options{})
+
+	w := send(router, http.MethodPatch, "/gadget/1", `{"Stock":0,"Active":false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var gadget Gadget
+	if err := db.First(&gadget, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if gadget.Stock != 0 || gadget.Active || gadget.Name != "g" || gadget.Note == nil {
+		t.Fatalf("expected zero values set and other fields kept, got %+v", gadget)
+	}
+	if gadget.CreatedAt.IsZero() {
+		t.Fatal("expected creation time to be kept")
+	}
+
+	// Explicit null clears nullable fields
+	if w := send(router, http.MethodPatch, "/gadget/1", `{"Note":null}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := db.First(&gadget, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if gadget.Note != nil {
+		t.Fatalf("expected note to be cleared, got %q", *gadget.Note)
+	}
+
+	for _, body := range []string{`{"Stock":null}`, `{"Stock":"many"}`, `[]`} {
+		if w := send(router, http.MethodPatch, "/gadget/1", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	if w := send(router, http.MethodPatch, "/gadget/9", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdatesLoadLockedInTransaction(t *testing.T) {
+	router, db := setupGadgets(t, Options{})
+
+	// Record how the gadget row is read before each update
+	type read struct{ locked, inTx bool }
+	var reads []read
+	err := db.Callback().Query().Before("gorm:query").Register("test:record", func(tx *gorm.DB) {
+		if tx.Statement.Table != "gadgets" {
+			return
+		}
+		_, locked := tx.Statement.Clauses["FOR"]
+		_, inTx := tx.Statement.ConnPool.(gorm.TxCommitter)
+		reads = append(reads, read{locked, inTx})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPut, "/gadget/1", strings.NewReader(`{"Name":"a"}`)),
+		httptest.NewRequest(http.MethodPatch, "/gadget/1", strings.NewReader(`{"Name":"b"}`)),
+		httptest.NewRequest(http.MethodPatch, "/gadget/1", strings.NewReader(`[{"op":"replace","path":"/Name","value":"c"}]`)),
+	}
+	requests[2].Header.Set("Content-Type", "application/json-patch+json")
+
+	for _, req := range requests {
+		reads = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", req.Method, w.Code, w.Body.String())
+		}
+		if len(reads) != 1 || !reads[0].locked || !reads[0].inTx {
+			t.Errorf("%s: expected one locked read in the transaction, got %+v", req.Method, reads)
+		}
+	}
+}
+
+func TestPutReplacesEntity(t *testing.T) {
+	router, db := setupGadgets(t, Options{})
+
+	w := send(router, http.MethodPut, "/gadget/1", `{"ID":7,"Name":"h"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var gadget Gadget
+	if err := db.First(&gadget, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if gadget.Name != "h" || gadget.Stock != 0 || gadget.Active || gadget.Note != nil {
+		t.Fatalf("expected omitted fields to be reset, got %+v", gadget)
+	}
+	if gadget.CreatedAt.IsZero() {
+		t.Fatal("expected creation time to be kept")
+	}
+
+	if w := send(router, http.MethodPut, "/gadget/1", `{"Stock":3}`); w.Code == http.StatusOK {
+		t.Fatal("expected BeforeUpdate to reject a replacement without name")
+	}
+}
+
+type Slot struct {
+	ID      uint `gorm:"primaryKey"`
+	CrateID *uint
+	Pos     int
+}
+
+type Sticker struct {
+	ID    uint `gorm:"primaryKey"`
+	Label string
+}
+
+type Crate struct {
+	ID       uint `gorm:"primaryKey"`
+	Name     string
+	Owner    string    `go-blar:"readonly"`
+	Secret   string    `go-blar:"hidden"`
+	Slots    []Slot    `go-blar:"list"`
+	Stickers []Sticker `go-blar:"m2m:crate_stickers" gorm:"many2many:crate_stickers"`
+}
+
+func TestJSONPatch(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Slot{}, &Sticker{}, &Crate{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Sticker{Label: "blue"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	crate := Crate{Name: "c", Owner: "ann", Slots: []Slot{{Pos: 1}, {Pos: 2}}, Stickers: []Sticker{{Label: "red"}}}
+	if err := db.Create(&crate).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Crate{Name: "other", Slots: []Slot{{Pos: 9}}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Crate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/crate/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch(`[
+		{"op": "test", "path": "/Name", "value": "c"},
+		{"op": "replace", "path": "/Name", "value": "d"},
+		{"op": "add", "path": "/Slots/-", "value": {"Pos": 3}},
+		{"op": "remove", "path": "/Slots/0"},
+		{"op": "add", "path": "/Stickers/0", "value": {"ID": 1}},
+		{"op": "test", "path": "/Stickers/1/Label", "value": "red"}
+	]`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected nested element path to be rejected, got %d", w.Code)
+	}
+
+	w = patch(`[
+		{"op": "test", "path": "/Name", "value": "c"},
+		{"op": "replace", "path": "/Name", "value": "d"},
+		{"op": "add", "path": "/Slots/-", "value": {"Pos": 3}},
+		{"op": "remove", "path": "/Slots/0"},
+		{"op": "add", "path": "/Stickers/0", "value": {"ID": 1}},
+		{"op": "test", "path": "/Stickers/1", "value": {"ID": 2, "Label": "red"}}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored Crate
+	if err := db.Preload("Slots").Preload("Stickers").First(&stored, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "d" || stored.Owner != "ann" {
+		t.Fatalf("expected name to be replaced, got %+v", stored)
+	}
+	if len(stored.Slots) != 2 || stored.Slots[0].Pos != 2 || stored.Slots[1].Pos != 3 {
+		t.Fatalf("expected slots 2 and 3, got %+v", stored.Slots)
+	}
+	if len(stored.Stickers) != 2 {
+		t.Fatalf("expected 2 stickers, got %+v", stored.Stickers)
+	}
+
+	// A failed test aborts the whole patch
+	w = patch(`[
+		{"op": "replace", "path": "/Name", "value": "e"},
+		{"op": "test", "path": "/Name", "value": "x"}
+	]`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := db.First(&stored, 1).Error; err != nil || stored.Name != "d" {
+		t.Fatalf("expected crate to be unchanged, got %+v", stored)
+	}
+
+	for _, body := range []string{
+		`[{"op": "replace", "path": "/Owner", "value": "bob"}]`,
+		`[{"op": "test", "path": "/Secret", "value": ""}]`,
+		`[{"op": "move", "from": "/Name", "path": "/Owner"}]`,
+		`[{"op": "remove", "path": "/Slots/5"}]`,
+		`[{"op": "replace", "path": "/NAME", "value": "e"}]`,
+		`[{"op": "add", "path": "/Slots/-", "value": {"ID": 3}}]`,
+		`[{"op": "replace", "path": "/Slots", "value": [{"ID": 3, "Pos": 1}]}]`,
+		`[{"op": "replace", "path": "/Name"}]`,
+		`{"op": "remove", "path": "/Name"}`,
+	} {
+		if w := patch(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	// Another crate's slot was not moved
+	var slot Slot
+	if err := db.First(&slot, 3).Error; err != nil || slot.CrateID == nil || *slot.CrateID != 2 {
+		t.Fatalf("expected slot 3 to stay on crate 2, got %+v", slot)
+	}
+}
+
+type Kid struct {
+	ID       uint   `gorm:"primaryKey"`
+	FamilyID uint   `go-blar:"validate:required"`
+	Name     string `go-blar:"validate:required"`
+	Nick     string
+	Pin      string `go-blar:"writeonly;transform:reverse"`
+}
+
+// BeforeCreate defaults the nickname to the name.
+func (k *Kid) BeforeCreate(ctx context.Context, tx *gorm.DB) error {
+	if k.Nick == "" {
+		k.Nick = k.Name
+	}
+	return nil
+}
+
+type Family struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+	Kids []Kid `go-blar:"list"`
+}
+
+func TestJSONPatchCreatesElements(t *testing.T) {
+	hooks.RegisterTransformer("reverse", func(ctx context.Context, value any) (any, error) {
+		r := []rune(value.(string))
+		slices.Reverse(r)
+		return string(r), nil
+	})
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Kid{}, &Family{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Family{Name: "f"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Family{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/family/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// New elements are validated as rows of their own entity type
+	w := patch(`[{"op": "add", "path": "/Kids/-", "value": {"Name": "", "Pin": "1234"}}]`)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"Kids/0/Name"`) {
+		t.Fatalf("expected status 422 for Kids/0/Name, got %d: %s", w.Code, w.Body.String())
+	}
+	var kids int64
+	if err := db.Model(&Kid{}).Count(&kids).Error; err != nil || kids != 0 {
+		t.Fatalf("expected no kids to be stored, got %d", kids)
+	}
+
+	// and are transformed and created with their create hooks
+	w = patch(`[{"op": "add", "path": "/Kids/-", "value": {"Name": "ann", "Pin": "1234"}}]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var kid Kid
+	if err := db.First(&kid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := (Kid{ID: 1, FamilyID: 1, Name: "ann", Nick: "ann", Pin: "4321"}); kid != want {
+		t.Fatalf("expected kid %+v, got %+v", want, kid)
+	}
+}
+
+type TagPrice struct {
+	ProductID uint `gorm:"primaryKey;autoIncrement:false"`
+	TagID     uint `gorm:"primaryKey;autoIncrement:false"`
+	Amount    float64
+}
+
+type Page struct {
+	Slug  string `gorm:"primaryKey"`
+	Title string
+}
+
+func TestNonIntegerAndCompositeKeys(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&TagPrice{}, &Page{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]TagPrice{{ProductID: 1, TagID: 2, Amount: 10}, {ProductID: 1, TagID: 3, Amount: 20}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]Page{{Slug: "hello world", Title: "Hello"}, {Slug: "50%,off", Title: "Sale"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	router := New()
+	handlers := NewHandlers(db, Options{DefaultPageSize: 20})
+	for _, model := range []any{&TagPrice{}, &Page{}} {
+		entityMeta, err := meta.Parse(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterEntityRoutes(router, entityMeta, handlers)
+	}
+
+	w := serve(router, http.MethodGet, "/tag-price/1,3")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Amount":20`) {
+		t.Fatalf("expected tag price 1,3, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := send(router, http.MethodPatch, "/tag-price/1,2", `{"Amount":15}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var prices []TagPrice
+	if err := db.Order("tag_id").Find(&prices).Error; err != nil {
+		t.Fatal(err)
+	}
+	if prices[0].Amount != 15 || prices[1].Amount != 20 {
+		t.Fatalf("expected only 1,2 to be updated, got %+v", prices)
+	}
+
+	if w := serve(router, http.MethodDelete, "/tag-price/1,3"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&TagPrice{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 tag price left, got %d", count)
+	}
+
+	w = serve(router, http.MethodGet, "/page/hello%20world")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Title":"Hello"`) {
+		t.Fatalf("expected page by slug, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, http.MethodGet, "/page/50%25%2Coff")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Title":"Sale"`) {
+		t.Fatalf("expected page by escaped slug, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, target := range []string{"/tag-price/1", "/tag-price/1,x", "/tag-price/1,2,3"} {
+		if w := serve(router, http.MethodGet, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+	if w := serve(router, http.MethodGet, "/page/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+type Audit struct {
+	ID     uint `gorm:"primaryKey"`
+	Action string
+}
+
+// Ledger records an Audit row in each After hook and fails it when named "fail".
+type Ledger struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func (l *Ledger) audit(tx *gorm.DB, action string) error {
+	if err := tx.Create(&Audit{Action: action}).Error; err != nil {
+		return err
+	}
+	if l.Name == "fail" {
+		return errors.New(action + " failed")
+	}
+	return nil
+}
+
+func (l *Ledger) AfterCreate(ctx context.Context, tx *gorm.DB) error {
+	return l.audit(tx, "create")
+}
+
+func (l *Ledger) AfterUpdate(ctx context.Context, tx *gorm.DB) error {
+	return l.audit(tx, "update")
+}
+
+func (l *Ledger) AfterDelete(ctx context.Context, tx *gorm.DB) error {
+	return l.audit(tx, "delete")
+}
+
+func TestHooksRunInTransaction(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Ledger{}, &Audit{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]Ledger{{Name: "ok"}, {Name: "fail"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Ledger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	// Failing After hooks roll back the write and the rows the hooks created
+	for _, req := range [][3]string{
+		{http.MethodPost, "/ledger", `{"Name":"fail"}`},
+		{http.MethodPatch, "/ledger/1", `{"Name":"fail"}`},
+		{http.MethodDelete, "/ledger/2", ``},
+	} {
+		if w := send(router, req[0], req[1], req[2]); w.Code != http.StatusInternalServerError {
+			t.Fatalf("%s %s: expected status 500, got %d", req[0], req[1], w.Code)
+		}
+	}
+
+	var ledgers []Ledger
+	if err := db.Order("id").Find(&ledgers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []Ledger{{ID: 1, Name: "ok"}, {ID: 2, Name: "fail"}}; !reflect.DeepEqual(ledgers, want) {
+		t.Fatalf("expected ledgers %v to be unchanged, got %v", want, ledgers)
+	}
+	var audits int64
+	if err := db.Model(&Audit{}).Count(&audits).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audits != 0 {
+		t.Fatalf("expected audit rows to be rolled back, got %d", audits)
+	}
+
+	// Successful hooks commit their rows with the write
+	if w := send(router, http.MethodPost, "/ledger", `{"Name":"new"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(router, http.MethodDelete, "/ledger/1", ``); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := db.Model(&Audit{}).Count(&audits).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audits != 2 {
+		t.Fatalf("expected 2 audit rows, got %d", audits)
+	}
+}
+
+// ownerKey is the context key of the current owner in TestReadHooks.
+type ownerKey struct{}
+
+// Memo is scoped to the owner in the request context and labeled after loading.
+type Memo struct {
+	ID    uint `gorm:"primaryKey"`
+	Owner string
+	Text  string
+	Label string `gorm:"-"`
+}
+
+func (m *Memo) scope(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	owner, ok := ctx.Value(ownerKey{}).(string)
+	if !ok {
+		return nil, &Error{Status: http.StatusUnauthorized}
+	}
+	return db.Where("owner = ?", owner), nil
+}
+
+func (m *Memo) BeforeList(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return m.scope(ctx, db)
+}
+
+func (m *Memo) BeforeGet(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return m.scope(ctx, db)
+}
+
+func (m *Memo) AfterList(ctx context.Context, tx *gorm.DB) error {
+	m.Label = "list:" + m.Text
+	return nil
+}
+
+func (m *Memo) AfterGet(ctx context.Context, tx *gorm.DB) error {
+	m.Label = "get:" + m.Text
+	return nil
+}
+
+func TestReadHooks(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Memo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]Memo{{Owner: "ann", Text: "a"}, {Owner: "bob", Text: "b"}, {Owner: "ann", Text: "c"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Memo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cursor := range []bool{false, true} {
+		opts := Options{DefaultPageSize: 20, CursorPagination: map[reflect.Type]bool{entityMeta.Type: cursor}}
+		router := New()
+		RegisterEntityRoutes(router, entityMeta, NewHandlers(db, opts))
+		as := func(owner, target string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if owner != "" {
+				req = req.WithContext(context.WithValue(req.Context(), ownerKey{}, owner))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := as("ann", "/memo")
+		var memos []Memo
+		if err := json.Unmarshal(w.Body.Bytes(), &memos); err != nil {
+			t.Fatalf("cursor=%v: %v: %s", cursor, err, w.Body.String())
+		}
+		if len(memos) != 2 || memos[0].Label != "list:a" || memos[1].Label != "list:c" {
+			t.Fatalf("cursor=%v: expected ann's labeled memos, got %+v", cursor, memos)
+		}
+		if cursor {
+			continue
+		}
+		if total := w.Header().Get("X-Total-Count"); total != "2" {
+			t.Fatalf("expected scoped total 2, got %s", total)
+		}
+
+		w = as("ann", "/memo/1")
+		var memo Memo
+		if err := json.Unmarshal(w.Body.Bytes(), &memo); err != nil || memo.Label != "get:a" {
+			t.Fatalf("expected labeled memo, got %+v, %v", memo, err)
+		}
+		if w := as("ann", "/memo/2"); w.Code != http.StatusNotFound {
+			t.Fatalf("expected another owner's memo to be hidden, got %d", w.Code)
+		}
+		if w := as("", "/memo"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 without owner, got %d", w.Code)
+		}
+	}
+
+	// Writes load the row with the same scope
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+	write := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(`{"Text":"x"}`))
+		req = req.WithContext(context.WithValue(req.Context(), ownerKey{}, "ann"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if w := write(method, "/memo/2"); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected another owner's memo to be hidden, got %d", method, w.Code)
+		}
+	}
+	var memo Memo
+	if err := db.First(&memo, 2).Error; err != nil || memo.Text != "b" {
+		t.Fatalf("expected bob's memo to be unchanged, got %+v, %v", memo, err)
+	}
+	if w := write(http.MethodPatch, "/memo/1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := write(http.MethodDelete, "/memo/3"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Profile has validation rules and a hook that must not run for invalid bodies.
+type Profile struct {
+	ID     uint   `gorm:"primaryKey"`
+	Handle string `json:"handle" go-blar:"validate:required,min=3,unique"`
+	Email  string `json:"email" go-blar:"validate:email"`
+	Plan   string `json:"plan" go-blar:"validate:oneof=free pro"`
+}
+
+func (p *Profile) BeforeCreate(ctx context.Context, tx *gorm.DB) error {
+	if p.Handle == "" {
+		return errors.New("hook ran before validation")
+	}
+	return nil
+}
+
+func TestValidationRules(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Profile{}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Profile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	if w := send(router, http.MethodPost, "/profile", `{"handle":"ann","email":"ann@x.io","plan":"pro"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		fields []FieldError
+	}{
+		{http.MethodPost, "/profile", `{"email":"nope","plan":"gold"}`, []FieldError{
+			{Field: "handle", Message: "is required"},
+			{Field: "email", Message: "must be a valid email address"},
+			{Field: "plan", Message: "must be one of free, pro"},
+		}},
+		{http.MethodPost, "/profile", `{"handle":"ann","plan":"free"}`, []FieldError{
+			{Field: "handle", Message: "must be unique"},
+			{Field: "email", Message: "must be a valid email address"},
+		}},
+		{http.MethodPatch, "/profile/1", `{"handle":"an"}`, []FieldError{
+			{Field: "handle", Message: "must have a length of at least 3"},
+		}},
+		{http.MethodPut, "/profile/1", `{"handle":"ann","email":"ann@x.io"}`, []FieldError{
+			{Field: "plan", Message: "must be one of free, pro"},
+		}},
+		{http.MethodPatch, "/profile/1", `[{"op":"replace","path":"/email","value":"x"}]`, []FieldError{
+			{Field: "email", Message: "must be a valid email address"},
+		}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if strings.HasPrefix(tt.body, "[") {
+			req.Header.Set("Content-Type", "application/json-patch+json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s %s: expected status 422, got %d: %s", tt.method, tt.body, w.Code, w.Body.String())
+			continue
+		}
+		if p := decodeProblem(t, w); !reflect.DeepEqual(p.Errors, tt.fields) {
+			t.Errorf("%s %s: expected field errors %v, got %v", tt.method, tt.body, tt.fields, p.Errors)
+		}
+	}
+
+	// Updating other fields keeps the entity's own unique value valid
+	if w := send(router, http.MethodPatch, "/profile/1", `{"plan":"free"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDanglingReferences(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Maker{}, &Gizmo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Gizmo{Name: "g", Maker: &Maker{Name: "acme"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Gizmo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	want := []FieldError{{Field: "MakerID", Message: "must reference an existing Maker"}}
+	for _, req := range [][3]string{
+		{http.MethodPost, "/gizmo", `{"Name":"h","MakerID":999}`},
+		{http.MethodPatch, "/gizmo/1", `{"MakerID":999}`},
+		{http.MethodPut, "/gizmo/1", `{"Name":"g","MakerID":999}`},
+	} {
+		w := send(router, req[0], req[1], req[2])
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s %s: expected status 422, got %d: %s", req[0], req[2], w.Code, w.Body.String())
+			continue
+		}
+		if p := decodeProblem(t, w); !reflect.DeepEqual(p.Errors, want) {
+			t.Errorf("%s %s: expected field errors %v, got %v", req[0], req[2], want, p.Errors)
+		}
+	}
+
+	if w := send(router, http.MethodPost, "/gizmo", `{"Name":"h","MakerID":1}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	if err := db.Model(&Gizmo{}).Where("maker_id = ?", 999).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("expected no dangling gizmos, got %d, %v", count, err)
+	}
+}
+
+type Patron struct {
+	ID     uint `gorm:"primaryKey"`
+	Name   string
+	Role   string `go-blar:"readonly"`
+	Secret string `go-blar:"hidden"`
+}
+
+type Receipt struct {
+	ID       uint `gorm:"primaryKey"`
+	PatronID *uint
+	Patron   *Patron
+	Lines    []ReceiptLine
+}
+
+type ReceiptLine struct {
+	ID        uint `gorm:"primaryKey"`
+	ReceiptID uint
+	Amount    int
+}
+
+func TestCreateIgnoresNestedRelations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Patron{}, &Receipt{}, &ReceiptLine{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Receipt{Lines: []ReceiptLine{{Amount: 100}}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Receipt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+	strict := New()
+	RegisterEntityRoutes(strict, entityMeta, NewHandlers(db, Options{ReadOnly: ReadOnlyReject}))
+
+	body := `{"Patron":{"ID":77,"Name":"p","Role":"admin","Secret":"s3"},"Lines":[{"ID":1,"Amount":1}]}`
+	if w := send(strict, http.MethodPost, "/receipt", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 under ReadOnlyReject, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := send(router, http.MethodPost, "/receipt", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Receipt
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Patron != nil || len(created.Lines) != 0 {
+		t.Fatalf("expected nested relations to be dropped from the response, got %s", w.Body.String())
+	}
+
+	var patrons int64
+	if err := db.Model(&Patron{}).Count(&patrons).Error; err != nil || patrons != 0 {
+		t.Fatalf("expected no patron to be created, got %d, %v", patrons, err)
+	}
+	var line ReceiptLine
+	if err := db.First(&line, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if line.ReceiptID != 1 || line.Amount != 100 {
+		t.Fatalf("expected existing line to stay on receipt 1, got %+v", line)
+	}
+}
+
+type Basket struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Items     []BasketItem
+	ItemCount int `go-blar:"count:Items" gorm:"-"`
+}
+
+type BasketItem struct {
+	ID       uint `gorm:"primaryKey"`
+	BasketID uint
+}
+
+func TestWriteResponsesComputeAggregates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Basket{}, &BasketItem{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Basket{Name: "a", Items: []BasketItem{{}, {}}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Basket{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	for _, tt := range []struct {
+		method, target, body string
+		status, count        int
+	}{
+		{http.MethodPut, "/basket/1", `{"Name":"b"}`, http.StatusOK, 2},
+		{http.MethodPatch, "/basket/1", `{"Name":"c"}`, http.StatusOK, 2},
+		{http.MethodPost, "/basket", `{"Name":"d"}`, http.StatusCreated, 0},
+	} {
+		w := send(router, tt.method, tt.target, tt.body)
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.method, tt.status, w.Code, w.Body.String())
+		}
+		var basket Basket
+		if err := json.Unmarshal(w.Body.Bytes(), &basket); err != nil || basket.ItemCount != tt.count {
+			t.Errorf("%s: expected ItemCount %d, got %+v, %v", tt.method, tt.count, basket, err)
+		}
+	}
+}
+
+type BoxBase struct {
+	Secret string `go-blar:"hidden"`
+	Note   string
+}
+
+type Boxed struct {
+	*BoxBase
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func TestEmbeddedPointerFields(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Boxed{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Boxed{BoxBase: &BoxBase{Secret: "s3cr3t", Note: "n"}, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Boxed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	RegisterEntityRoutes(router, entityMeta, NewHandlers(db, Options{}))
+
+	w := serve(router, http.MethodGet, "/boxed/1")
+	if got := strings.TrimSpace(w.Body.String()); got != `{"Note":"n","ID":1,"Name":"a"}` {
+		t.Fatalf("unexpected Get body: %s", got)
+	}
+
+	w = send(router, http.MethodPost, "/boxed", `{"Secret":"injected","Name":"b"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"Note":"","ID":2,"Name":"b"}` {
+		t.Fatalf("unexpected Create body: %s", got)
+	}
+	var stored Boxed
+	if err := db.First(&stored, 2).Error; err != nil || (stored.BoxBase != nil && stored.Secret != "") {
+		t.Fatalf("expected hidden field not to be written, got %+v, %v", stored.BoxBase, err)
+	}
+
+	if w := send(router, http.MethodPatch, "/boxed/2", `{"Note":"m"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := db.First(&stored, 2).Error; err != nil || stored.Note != "m" {
+		t.Fatalf("expected note to be patched, got %+v, %v", stored.BoxBase, err)
+	}
+}
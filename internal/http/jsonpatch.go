@@ -0,0 +1,405 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"github.com/kamil5b/go-blar/internal/query"
+	"gorm.io/gorm/schema"
+)
+
+// jsonPatchType is the media type of RFC 6902 JSON Patch documents.
+const jsonPatchType = "application/json-patch+json"
+
+// errPatchTest is returned when a JSON Patch test operation does not match.
+var errPatchTest = errors.New("test operation failed")
+
+// patchOp is a single RFC 6902 operation.
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// patchTarget is the part of an entity a JSON Pointer refers to:
+// a column field, a list/m2m collection, or an element of a collection.
+type patchTarget struct {
+	field    *meta.FieldMeta
+	relation *meta.RelationMeta
+	index    string // collection element: a decimal index or "-"; empty for the whole field
+}
+
+// jsonPatch is a parsed JSON Patch document.
+type jsonPatch struct {
+	ops     []patchOp
+	targets []patchTarget
+}
+
+// parseJSONPatch reads a JSON Patch document and resolves its paths against the
+// entity metadata. Only readable fields can be addressed; add, remove and
+// replace also require writable columns or list/m2m collections.
+func parseJSONPatch(r io.Reader, entityMeta *meta.EntityMeta) (*jsonPatch, error) {
+	var ops []patchOp
+	if err := json.NewDecoder(r).Decode(&ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch document: %w", err)
+	}
+
+	patch := &jsonPatch{ops: ops, targets: make([]patchTarget, 0, len(ops))}
+	for i, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("operation %d (%s): value is required", i, op.Op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d: unsupported op %q", i, op.Op)
+		}
+
+		target, err := resolvePointer(entityMeta, op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+		if target.relation == nil && target.index != "" {
+			return nil, fmt.Errorf("operation %d (%s): invalid path %q", i, op.Op, op.Path)
+		}
+		patch.targets = append(patch.targets, target)
+	}
+
+	return patch, nil
+}
+
+// relations returns the collections the patch refers to, which must be loaded.
+func (p *jsonPatch) relations() []*meta.RelationMeta {
+	relations := make([]*meta.RelationMeta, 0)
+	for _, t := range p.targets {
+		if t.relation != nil && !slices.Contains(relations, t.relation) {
+			relations = append(relations, t.relation)
+		}
+	}
+	return relations
+}
+
+// apply applies the operations in order to entity, a pointer to the loaded entity
+// with the patched collections preloaded. It returns the column fields written,
+// the collections changed and the elements added to them without a primary
+// key; nothing is saved. Test failures wrap errPatchTest.
+func (p *jsonPatch) apply(sch *schema.Schema, entity any, policy ReadOnlyPolicy) (changes, error) {
+	v := reflect.ValueOf(entity).Elem()
+	ch := changes{written: make([]*meta.FieldMeta, 0), collections: make([]*meta.RelationMeta, 0)}
+	owned := make(map[*meta.RelationMeta]map[string]bool)
+
+	// The fields each collection element's value set, or nil for loaded elements
+	decoded := make(map[*meta.RelationMeta][][]*meta.FieldMeta)
+
+	for i, op := range p.ops {
+		target := p.targets[i]
+		if rel := target.relation; rel != nil && decoded[rel] == nil {
+			collection := rel.Field.Value(v)
+			decoded[rel] = make([][]*meta.FieldMeta, collection.Len())
+			if rel.Kind == "list" {
+				owned[rel] = elementKeys(rel, collection)
+			}
+		}
+
+		var err error
+		if op.Op == "test" {
+			err = testValue(v, target, op.Value)
+		} else if target.relation != nil {
+			if !target.field.Writable() {
+				err = fmt.Errorf("field %q cannot be patched", target.field.JSONName)
+			} else {
+				fields := decoded[target.relation]
+				err = applyCollection(v, target, op, policy, &fields)
+				decoded[target.relation] = fields
+			}
+			if err == nil && !slices.Contains(ch.collections, target.relation) {
+				ch.collections = append(ch.collections, target.relation)
+			}
+		} else if !replaceable(sch, target.field) {
+			err = fmt.Errorf("field %q cannot be patched", target.field.JSONName)
+		} else {
+			err = applyField(target.field.Settable(v), target.field, op)
+			if err == nil && !slices.Contains(ch.written, target.field) {
+				ch.written = append(ch.written, target.field)
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, errPatchTest) {
+				return changes{}, fmt.Errorf("operation %d (test %s): %w", i, op.Path, err)
+			}
+			return changes{}, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	for _, rel := range ch.collections {
+		collection := rel.Field.Value(v)
+
+		// Saving a list sets the foreign key of every element, so elements with
+		// a key must already belong to this entity
+		if rel.Kind == "list" {
+			for key := range elementKeys(rel, collection) {
+				if !owned[rel][key] {
+					return changes{}, fmt.Errorf("%s element %s does not belong to this entity", rel.Field.JSONName, key)
+				}
+			}
+		}
+
+		// Decoded elements without a key are new rows
+		for i, fields := range decoded[rel] {
+			elem := elementAt(collection, i)
+			if fields == nil || elem == nil {
+				continue
+			}
+			if _, ok := elementKey(rel, elem); !ok {
+				ch.added = append(ch.added, addedElement{relation: rel, index: i, written: fields})
+			}
+		}
+	}
+
+	return ch, nil
+}
+
+// elementKeys returns the formatted primary keys of the elements in collection
+// that have one. Elements with a zero key are new rows.
+func elementKeys(rel *meta.RelationMeta, collection reflect.Value) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < collection.Len(); i++ {
+		if elem := elementAt(collection, i); elem != nil {
+			if key, ok := elementKey(rel, elem); ok {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// elementKey returns the formatted primary key of elem, a pointer to a
+// collection element, and whether it has a non-zero one.
+func elementKey(rel *meta.RelationMeta, elem any) (string, bool) {
+	values := query.KeyOf(rel.Entity, elem)
+	if !slices.ContainsFunc(values, func(v any) bool { return !reflect.ValueOf(v).IsZero() }) {
+		return "", false
+	}
+	return fmt.Sprint(values...), true
+}
+
+// elementAt returns a pointer to element i of collection, or nil for a nil pointer.
+func elementAt(collection reflect.Value, i int) any {
+	elem := collection.Index(i)
+	if elem.Kind() != reflect.Ptr {
+		return elem.Addr().Interface()
+	}
+	if elem.IsNil() {
+		return nil
+	}
+	return elem.Interface()
+}
+
+// resolvePointer resolves a JSON Pointer such as /name, /tags or /tags/0.
+func resolvePointer(entityMeta *meta.EntityMeta, pointer string) (patchTarget, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return patchTarget{}, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	if len(tokens) > 2 {
+		return patchTarget{}, fmt.Errorf("unsupported path %q", pointer)
+	}
+
+	// JSON Pointers are case-sensitive, unlike encoding/json keys
+	f := entityMeta.GetFieldByJSONName(tokens[0])
+	if f == nil || !f.Readable() {
+		return patchTarget{}, fmt.Errorf("unknown path %q", pointer)
+	}
+
+	target := patchTarget{field: f}
+	for _, rel := range entityMeta.Relations {
+		if rel.Field == f && (rel.Kind == "list" || rel.Kind == "m2m") {
+			target.relation = rel
+		}
+	}
+	if target.relation == nil && !f.IsColumn() {
+		return patchTarget{}, fmt.Errorf("unsupported path %q", pointer)
+	}
+
+	if len(tokens) == 2 {
+		target.index = tokens[1]
+	}
+	return target, nil
+}
+
+// applyField applies add, replace or remove to a column field.
+// Remove clears a nullable field.
+func applyField(field reflect.Value, f *meta.FieldMeta, op patchOp) error {
+	if op.Op == "remove" || string(op.Value) == "null" {
+		if !f.Nullable() {
+			return fmt.Errorf("field %q is not nullable", f.JSONName)
+		}
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	value := reflect.New(field.Type())
+	if err := json.Unmarshal(op.Value, value.Interface()); err != nil {
+		return err
+	}
+	field.Set(value.Elem())
+	return nil
+}
+
+// applyCollection applies add, replace or remove to a list/m2m collection or one
+// of its elements. Elements are decoded with the related entity's metadata.
+// decoded holds the fields each element's value set, nil for loaded elements,
+// and is updated in step with the collection.
+func applyCollection(v reflect.Value, target patchTarget, op patchOp, policy ReadOnlyPolicy, decoded *[][]*meta.FieldMeta) error {
+	rel := target.relation
+	field := rel.Field.Settable(v)
+	elemType := field.Type().Elem()
+
+	// Whole collection: add and replace set it, remove clears it
+	if target.index == "" {
+		if op.Op == "remove" {
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+			*decoded = nil
+			return nil
+		}
+
+		var raws []json.RawMessage
+		if err := json.Unmarshal(op.Value, &raws); err != nil {
+			return fmt.Errorf("%s must be an array", rel.Field.JSONName)
+		}
+		items := reflect.MakeSlice(field.Type(), 0, len(raws))
+		fields := make([][]*meta.FieldMeta, 0, len(raws))
+		for _, raw := range raws {
+			elem, written, err := decodeElement(rel, elemType, raw, policy)
+			if err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+			fields = append(fields, written)
+		}
+		field.Set(items)
+		*decoded = fields
+		return nil
+	}
+
+	n := field.Len()
+	index := n
+	if target.index != "-" || op.Op != "add" {
+		i, err := parseIndex(target.index)
+		if err != nil {
+			return err
+		}
+		if i > n || (i == n && op.Op != "add") {
+			return fmt.Errorf("index %d is out of range", i)
+		}
+		index = i
+	}
+
+	items := reflect.MakeSlice(field.Type(), 0, n+1)
+	items = reflect.AppendSlice(items, field.Slice(0, index))
+	fields := slices.Clone((*decoded)[:index])
+
+	switch op.Op {
+	case "add", "replace":
+		elem, written, err := decodeElement(rel, elemType, op.Value, policy)
+		if err != nil {
+			return err
+		}
+		items = reflect.Append(items, elem)
+		fields = append(fields, written)
+		if op.Op == "add" {
+			items = reflect.AppendSlice(items, field.Slice(index, n))
+			fields = append(fields, (*decoded)[index:]...)
+		} else {
+			items = reflect.AppendSlice(items, field.Slice(index+1, n))
+			fields = append(fields, (*decoded)[index+1:]...)
+		}
+	case "remove":
+		items = reflect.AppendSlice(items, field.Slice(index+1, n))
+		fields = append(fields, (*decoded)[index+1:]...)
+	}
+
+	field.Set(items)
+	*decoded = fields
+	return nil
+}
+
+// decodeElement decodes a collection element of type elemType, a struct or pointer
+// to struct, and returns it with the fields its value set.
+func decodeElement(rel *meta.RelationMeta, elemType reflect.Type, raw json.RawMessage, policy ReadOnlyPolicy) (reflect.Value, []*meta.FieldMeta, error) {
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	elem := reflect.New(structType)
+	written, err := decode(bytes.NewReader(raw), rel.Entity, elem.Interface(), policy)
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	if elemType.Kind() == reflect.Ptr {
+		return elem, written, nil
+	}
+	return elem.Elem(), written, nil
+}
+
+// testValue compares the serialized value at target with expected.
+func testValue(v reflect.Value, target patchTarget, expected json.RawMessage) error {
+	field := target.field.Value(v)
+
+	var actual any = field.Interface()
+	if target.relation != nil {
+		if target.index != "" {
+			i, err := parseIndex(target.index)
+			if err != nil {
+				return err
+			}
+			if i >= field.Len() {
+				return errPatchTest
+			}
+			field = field.Index(i)
+		}
+		actual = serializeValue(target.relation.Entity, field, nil)
+	}
+
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		return err
+	}
+
+	var a, e any
+	if err := json.Unmarshal(actualJSON, &a); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(expected, &e); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(a, e) {
+		return errPatchTest
+	}
+	return nil
+}
+
+// parseIndex parses an array index token of a JSON Pointer.
+func parseIndex(token string) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
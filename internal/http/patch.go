@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// deletedAtType is the type of GORM soft delete columns.
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// replaceable reports whether PUT and PATCH may change field f: a writable
+// column other than the primary key and the timestamps GORM maintains itself.
+func replaceable(sch *schema.Schema, f *meta.FieldMeta) bool {
+	if !f.IsColumn() || f.IsPK || !f.Writable() {
+		return false
+	}
+	if sf := sch.LookUpField(f.Name); sf != nil {
+		return sf.AutoCreateTime == 0 && sf.AutoUpdateTime == 0 && sf.FieldType != deletedAtType
+	}
+	return true
+}
+
+// replace copies the replaceable fields of replacement into entity, both pointers
+// to structs, for a PUT. Write-only fields are only copied when the body set
+// them, since clients cannot read them back to resend them.
+func replace(sch *schema.Schema, entityMeta *meta.EntityMeta, entity, replacement any, written []*meta.FieldMeta) {
+	current := reflect.ValueOf(entity).Elem()
+	next := reflect.ValueOf(replacement).Elem()
+
+	for _, f := range entityMeta.Fields {
+		if !replaceable(sch, f) || (f.WriteOnly && !slices.Contains(written, f)) {
+			continue
+		}
+		f.Settable(current).Set(f.Value(next))
+	}
+}
+
+// mergePatch applies an RFC 7396 JSON merge patch read from r to entity, a
+// pointer to the loaded entity, and returns the fields it set. A null member
+// clears a nullable field; keys of fields PUT cannot replace are dropped, or
+// rejected under ReadOnlyReject.
+func mergePatch(r io.Reader, sch *schema.Schema, entityMeta *meta.EntityMeta, entity any, policy ReadOnlyPolicy) ([]*meta.FieldMeta, error) {
+	body, written, err := readBody(r, entityMeta, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(entity).Elem()
+	patched := make([]*meta.FieldMeta, 0, len(written))
+
+	for key, raw := range body {
+		f := fieldByJSONName(entityMeta, key)
+		if f == nil {
+			continue
+		}
+		if !replaceable(sch, f) {
+			if policy == ReadOnlyReject {
+				return nil, fieldError{field: key, reason: "cannot be patched"}
+			}
+			continue
+		}
+
+		field := f.Settable(v)
+		if string(raw) == "null" {
+			if !f.Nullable() {
+				return nil, fieldError{field: key, reason: "is not nullable"}
+			}
+			field.Set(reflect.Zero(field.Type()))
+		} else {
+			value := reflect.New(field.Type())
+			if err := json.Unmarshal(raw, value.Interface()); err != nil {
+				return nil, err
+			}
+			field.Set(value.Elem())
+		}
+		patched = append(patched, f)
+	}
+
+	return patched, nil
+}
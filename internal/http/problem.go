@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/kamil5b/go-blar/internal/validate"
+)
+
+// problemContentType is the media type of RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// Error is an error with an HTTP status, written to clients as an RFC 7807
+// problem. Hooks can return one to choose the response status and message.
+type Error struct {
+	Status int          // HTTP status code
+	Type   string       // URI identifying the problem type; "about:blank" if empty
+	Title  string       // short summary; the status text if empty
+	Detail string       // explanation specific to this occurrence
+	Fields []FieldError // per-field validation errors
+	Err    error        // underlying cause; logged, never sent to clients
+}
+
+// FieldError describes an invalid field in a request.
+type FieldError = validate.FieldError
+
+// Error returns the detail, or the cause or title when there is none.
+func (e *Error) Error() string {
+	switch {
+	case e.Detail != "":
+		return e.Detail
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return e.title()
+	}
+}
+
+// Unwrap returns the underlying cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// title returns the title, defaulting to the status text.
+func (e *Error) title() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return http.StatusText(e.Status)
+}
+
+// problem is the JSON body of a problem+json response.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// newError returns an *Error with the given status and client-facing detail.
+func newError(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
+
+// badRequest wraps a request parsing error, whose message is safe to show, as a 400.
+func badRequest(err error) *Error {
+	return &Error{Status: http.StatusBadRequest, Detail: err.Error(), Err: err}
+}
+
+// writeError writes err as a problem+json response. Validation errors become a
+// 422 listing the invalid fields. An *Error found with
+// errors.As sets the status; if it was wrapped, as in
+// fmt.Errorf("%w: price is negative", ErrValidation), the whole message becomes
+// the detail. Other errors, such as database errors, become a 500 whose details
+// are logged but not sent.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var fields validate.Errors
+	if errors.As(err, &fields) {
+		err = &Error{Status: http.StatusUnprocessableEntity, Detail: "validation failed", Fields: fields}
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Status < 400 {
+		log.Printf("go-blar: %s %s: %v", r.Method, r.URL.Path, err)
+		e = &Error{Status: http.StatusInternalServerError, Detail: "an internal error occurred"}
+	} else if e.Err != nil && e.Status >= 500 {
+		log.Printf("go-blar: %s %s: %v", r.Method, r.URL.Path, e.Err)
+	}
+
+	detail := e.Detail
+	if err != error(e) && e.Status < 500 {
+		detail = err.Error()
+	}
+
+	body := problem{
+		Type:     e.Type,
+		Title:    e.title(),
+		Status:   e.Status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   e.Fields,
+	}
+	if body.Type == "" {
+		body.Type = "about:blank"
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(body)
+}
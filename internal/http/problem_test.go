@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// decodeProblem checks the response is a problem+json document and decodes it.
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("expected Content-Type %s, got %q", problemContentType, ct)
+	}
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid problem body %s: %v", w.Body.String(), err)
+	}
+	if p.Status != w.Code {
+		t.Fatalf("expected status %d in body, got %d", w.Code, p.Status)
+	}
+	return p
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		title  string
+		detail string
+	}{
+		{"error", &Error{Status: http.StatusUnprocessableEntity, Detail: "order is closed"}, 422, "Unprocessable Entity", "order is closed"},
+		{"wrapped", fmt.Errorf("%w: price is negative", &Error{Status: http.StatusUnprocessableEntity, Detail: "validation failed"}), 422, "Unprocessable Entity", "validation failed: price is negative"},
+		{"title", &Error{Status: http.StatusForbidden, Title: "Denied"}, 403, "Denied", ""},
+		{"internal", errors.New("UNIQUE constraint failed: users.email"), 500, "Internal Server Error", "an internal error occurred"},
+		{"invalid status", &Error{Detail: "no status"}, 500, "Internal Server Error", "an internal error occurred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeError(w, httptest.NewRequest(http.MethodPost, "/user", nil), tt.err)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			p := decodeProblem(t, w)
+			if p.Type != "about:blank" || p.Title != tt.title || p.Detail != tt.detail || p.Instance != "/user" {
+				t.Fatalf("unexpected problem %+v", p)
+			}
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	router, _ := setupRouter(t, Options{DefaultPageSize: 20, ReadOnly: ReadOnlyReject}, testWidgets()...)
+
+	w := serve(router, http.MethodGet, "/widget?sort=unknown")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if p := decodeProblem(t, w); p.Title != "Bad Request" || p.Detail == "" || p.Instance != "/widget" {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+
+	w = serve(router, http.MethodGet, "/widget/99")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+	decodeProblem(t, w)
+
+	w = send(router, http.MethodPost, "/widget", `{"Name":"f","code":"F1"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	want := []FieldError{{Field: "code", Message: "is read-only"}}
+	if p := decodeProblem(t, w); !reflect.DeepEqual(p.Errors, want) {
+		t.Fatalf("expected field errors %v, got %v", want, p.Errors)
+	}
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"net/http"
+	"strings"
+	"unicode"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kamil5b/go-blar/internal/meta"
@@ -27,9 +29,11 @@ func (r *Router) AddMiddleware(m func(http.Handler) http.Handler) {
 }
 
 // ApplyMiddleware applies all registered middlewares to the router.
+// Middlewares run in the order they were added, so the first one is the
+// outermost. It must be called before any routes are registered.
 func (r *Router) ApplyMiddleware() {
-	for i := len(r.middlewares) - 1; i >= 0; i-- {
-		r.Use(r.middlewares[i])
+	for _, m := range r.middlewares {
+		r.Use(m)
 	}
 }
 
@@ -51,18 +55,27 @@ func RegisterEntityRoutes(router *Router, meta *meta.EntityMeta, handlers *Handl
 	// PUT /resource/{id}
 	router.Put("/"+resourceName+"/{id}", handlers.UpdateHandler(meta))
 
+	// PATCH /resource/{id}
+	router.Patch("/"+resourceName+"/{id}", handlers.PatchHandler(meta))
+
 	// DELETE /resource/{id}
 	router.Delete("/"+resourceName+"/{id}", handlers.DeleteHandler(meta))
 }
 
 // toURLPath converts a CamelCase entity name to a kebab-case URL path.
 func toURLPath(s string) string {
-	var result []byte
-	for i, r := range s {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result = append(result, '-')
+	var result strings.Builder
+	runes := []rune(s)
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				result.WriteRune('-')
+			}
 		}
-		result = append(result, byte(r+'a'-'A'+1)) // Simple lowercase
+		result.WriteRune(unicode.ToLower(r))
 	}
-	return string(result)
+	return result.String()
 }
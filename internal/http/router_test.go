@@ -0,0 +1,23 @@
+package http
+
+import "testing"
+
+func TestToURLPath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Product", "product"},
+		{"ProductItem", "product-item"},
+		{"ProductToPrice", "product-to-price"},
+		{"HTTPServer", "http-server"},
+		{"UserID", "user-id"},
+	}
+
+	for _, tt := range tests {
+		result := toURLPath(tt.input)
+		if result != tt.expected {
+			t.Errorf("toURLPath(%s) = %s, expected %s", tt.input, result, tt.expected)
+		}
+	}
+}
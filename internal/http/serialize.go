@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+)
+
+// object is a JSON object that keeps its keys in struct field order.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// MarshalJSON encodes the object with its keys in order.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// serialize shapes entities, a pointer to a struct or to a slice of structs, for a response.
+// Hidden and write-only fields are always left out, and with a fieldset only those fields are included.
+// Included relations are serialized with the metadata of the related entity.
+func serialize(entityMeta *meta.EntityMeta, entities any, fields []*meta.FieldMeta) any {
+	return serializeValue(entityMeta, reflect.ValueOf(entities), fields)
+}
+
+// serializeValue serializes a struct, a slice of structs, or a pointer to either.
+// Nil pointers and slices serialize as null.
+func serializeValue(entityMeta *meta.EntityMeta, v reflect.Value, fields []*meta.FieldMeta) any {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return serializeOne(entityMeta, v, fields)
+	}
+
+	if v.IsNil() {
+		return nil
+	}
+	objects := make([]any, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		objects = append(objects, serializeValue(entityMeta, v.Index(i), fields))
+	}
+	return objects
+}
+
+// serializeOne builds the JSON object for a single entity struct value.
+func serializeOne(entityMeta *meta.EntityMeta, v reflect.Value, fields []*meta.FieldMeta) object {
+	obj := object{values: make(map[string]any)}
+
+	for _, f := range entityMeta.Fields {
+		if f.JSONName == "" || !f.Readable() || (fields != nil && !slices.Contains(fields, f)) {
+			continue
+		}
+
+		value := f.Value(v)
+		if f.OmitEmpty && value.IsZero() {
+			continue
+		}
+
+		obj.keys = append(obj.keys, f.JSONName)
+		if rel := relationOf(entityMeta, f); rel != nil {
+			obj.values[f.JSONName] = serializeValue(rel.Entity, value, nil)
+		} else {
+			obj.values[f.JSONName] = value.Interface()
+		}
+	}
+
+	return obj
+}
+
+// relationOf returns the relation stored in field f, if any.
+func relationOf(entityMeta *meta.EntityMeta, f *meta.FieldMeta) *meta.RelationMeta {
+	for _, rel := range entityMeta.Relations {
+		if rel.Field == f {
+			return rel
+		}
+	}
+	return nil
+}
+
+// fieldError reports a request body key that cannot be written.
+type fieldError struct {
+	field  string
+	reason string
+}
+
+func (e fieldError) Error() string {
+	return fmt.Sprintf("field %q %s", e.field, e.reason)
+}
+
+// decode decodes a JSON object from the request body into entity and returns the
+// fields it set. Keys of hidden, read-only and aggregate fields are dropped, or
+// rejected under ReadOnlyReject.
+func decode(r io.Reader, entityMeta *meta.EntityMeta, entity any, policy ReadOnlyPolicy) ([]*meta.FieldMeta, error) {
+	body, written, err := readBody(r, entityMeta, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return written, json.Unmarshal(data, entity)
+}
+
+// readBody reads a JSON object from the request body, with the keys of fields
+// clients cannot write dropped (or rejected under ReadOnlyReject), and returns
+// it together with the fields its keys set. Relations are dropped too, since
+// GORM would save nested objects without the related entity's field checks
+// and could move existing child rows to another parent; JSON Patch sets
+// collections instead.
+func readBody(r io.Reader, entityMeta *meta.EntityMeta, policy ReadOnlyPolicy) (map[string]json.RawMessage, []*meta.FieldMeta, error) {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return nil, nil, err
+	}
+	if body == nil {
+		return nil, nil, errors.New("request body must be a JSON object")
+	}
+
+	written := make([]*meta.FieldMeta, 0, len(body))
+	for key := range body {
+		f := fieldByJSONName(entityMeta, key)
+		if f == nil {
+			continue
+		}
+		if f.Writable() && !f.IsRelation() {
+			written = append(written, f)
+			continue
+		}
+		if policy == ReadOnlyReject {
+			reason := "is read-only"
+			if f.IsRelation() {
+				reason = "is a relation and cannot be set"
+			}
+			return nil, nil, fieldError{field: key, reason: reason}
+		}
+		delete(body, key)
+	}
+
+	return body, written, nil
+}
+
+// fieldByJSONName returns the field encoding/json would decode key into:
+// an exact match of its JSON key, or else a case-insensitive one.
+func fieldByJSONName(entityMeta *meta.EntityMeta, key string) *meta.FieldMeta {
+	var fold *meta.FieldMeta
+	for _, f := range entityMeta.Fields {
+		if f.JSONName == "" {
+			continue
+		}
+		if f.JSONName == key {
+			return f
+		}
+		if fold == nil && strings.EqualFold(f.JSONName, key) {
+			fold = f
+		}
+	}
+	return fold
+}
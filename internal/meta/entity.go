@@ -1,25 +1,46 @@
 package meta
 
-import "reflect"
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
 
 // FieldMeta holds metadata about a single field in an entity.
 type FieldMeta struct {
-	Name     string
-	Type     reflect.Type
-	Index    []int // NestedIndex for embedded structs
-	IsPK     bool
-	FK       *ForeignKey
-	Nested   bool
-	M2M      *ManyToMany
-	List     bool
-	Hidden   bool
-	ReadOnly bool
+	Name      string
+	Column    string // database column; empty for fields tagged gorm:"-"
+	JSONName  string // JSON object key; empty for fields tagged json:"-"
+	OmitEmpty bool   // json:",omitempty"
+	Type      reflect.Type
+	Index     []int // NestedIndex for embedded structs
+	IsPK      bool
+	FK        *ForeignKey
+	Nested    bool
+	M2M       *ManyToMany
+	List      bool
+	Hidden    bool
+	ReadOnly  bool
+	WriteOnly bool   // accepted on write but never returned
+	Transform string // transformer applied to written values, e.g. "hash"
+	Include   bool   // relation is expanded without ?include=
+	Validate  []Rule // validation rules from validate:required,max=50
+
+	// Aggregate is set when the field is computed by a count:/sum: directive.
+	Aggregate *AggregateMeta
+}
+
+// Rule is a validation rule of a field, such as "required" or "max=50".
+type Rule struct {
+	Name  string
+	Param string // text after "=", e.g. "50"; empty for rules without one
 }
 
 // ForeignKey holds metadata for a foreign key relationship.
 type ForeignKey struct {
 	TableName string
 	FieldName string
+	Entity    *EntityMeta // referenced entity, set by ResolveForeignKeys
 }
 
 // ManyToMany holds metadata for a many-to-many relationship.
@@ -28,6 +49,17 @@ type ManyToMany struct {
 	FieldName string
 }
 
+// RelationMeta describes a relation that can be expanded with ?include=.
+type RelationMeta struct {
+	Name    string      // struct field holding the related entity or entities, e.g. "User"
+	Param   string      // name used in ?include=, e.g. "user"
+	Kind    string      // "fk", "m2m" or "list"
+	Field   *FieldMeta  // the relation field
+	Key     *FieldMeta  // foreign key column of an fk relation
+	Entity  *EntityMeta // metadata of the related entity
+	Default bool        // included without being requested
+}
+
 // NestedMeta holds metadata about a nested/embedded struct.
 type NestedMeta struct {
 	Name  string
@@ -37,9 +69,11 @@ type NestedMeta struct {
 
 // AggregateMeta holds metadata about an aggregate field.
 type AggregateMeta struct {
-	Name  string
-	Type  string // "count", "sum", etc.
-	Field string // nested field path
+	Name     string
+	Type     string // "count", "countDistinct", "sum", "avg", "min" or "max"
+	Relation string // has-many relation the aggregate runs over, e.g. "Items"
+	Field    string // nested field path or expression, e.g. "Items.Price*Items.Quantity"
+	Filter   string // optional condition on related rows, e.g. "Quantity>0"
 }
 
 // EntityMeta holds all metadata about an entity type.
@@ -47,10 +81,12 @@ type EntityMeta struct {
 	Type       reflect.Type
 	Name       string
 	TableName  string
-	PKField    *FieldMeta
+	PKField    *FieldMeta   // first primary key field
+	PKFields   []*FieldMeta // all primary key fields, in order; several for composite keys
 	Fields     []*FieldMeta
 	Nested     []*NestedMeta
 	Aggregates []*AggregateMeta
+	Relations  []*RelationMeta
 }
 
 // GetFieldByName returns a field by its name.
@@ -63,6 +99,150 @@ func (em *EntityMeta) GetFieldByName(name string) *FieldMeta {
 	return nil
 }
 
+// GetFieldByColumn returns a field by its database column name.
+func (em *EntityMeta) GetFieldByColumn(column string) *FieldMeta {
+	for _, f := range em.Fields {
+		if f.Column != "" && f.Column == column {
+			return f
+		}
+	}
+	return nil
+}
+
+// GetFieldByJSONName returns a field by its exact JSON object key.
+func (em *EntityMeta) GetFieldByJSONName(name string) *FieldMeta {
+	for _, f := range em.Fields {
+		if f.JSONName != "" && f.JSONName == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// GetFieldByParam returns a field by the name clients use for it in query
+// parameters: its column, or the snake_case field name for fields without one.
+func (em *EntityMeta) GetFieldByParam(name string) *FieldMeta {
+	if f := em.GetFieldByColumn(name); f != nil {
+		return f
+	}
+	for _, f := range em.Fields {
+		if f.Column == "" && toSnakeCase(f.Name) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// GetRelationByParam returns a relation by its ?include= name.
+func (em *EntityMeta) GetRelationByParam(name string) *RelationMeta {
+	for _, r := range em.Relations {
+		if r.Param == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// ReferencedEntity returns the entity the fk: field f refers to: the entity of
+// its relation, or else the one ResolveForeignKeys found for the tag. It returns
+// nil if f is not a foreign key or its entity is unknown.
+func (em *EntityMeta) ReferencedEntity(f *FieldMeta) *EntityMeta {
+	if f.FK == nil {
+		return nil
+	}
+	for _, rel := range em.Relations {
+		if rel.Key == f {
+			return rel.Entity
+		}
+	}
+	return f.FK.Entity
+}
+
+// Readable reports whether clients may see the field: it is neither hidden nor write-only.
+func (f *FieldMeta) Readable() bool {
+	return !f.Hidden && !f.WriteOnly
+}
+
+// Writable reports whether clients may set the field: it is not hidden,
+// read-only or an aggregate.
+func (f *FieldMeta) Writable() bool {
+	return !f.Hidden && !f.ReadOnly && f.Aggregate == nil
+}
+
+// Nullable reports whether the field can be cleared to NULL: pointers, slices,
+// maps, interfaces and sql.Null* style valuers.
+func (f *FieldMeta) Nullable() bool {
+	switch f.Type.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		return f.Type.Implements(valuerType) || reflect.PointerTo(f.Type).Implements(valuerType)
+	default:
+		return false
+	}
+}
+
+// IsColumn reports whether the field is stored in a table column,
+// as opposed to a relation, an aggregate, or a field tagged gorm:"-".
+func (f *FieldMeta) IsColumn() bool {
+	if f.Column == "" || f.Aggregate != nil {
+		return false
+	}
+
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return false
+	case reflect.Struct:
+		return t == timeType || t.Implements(valuerType) || reflect.PointerTo(t).Implements(valuerType)
+	default:
+		return true
+	}
+}
+
+// Value returns the field of v, an entity struct value. Fields of a nil
+// embedded pointer read as the zero value.
+func (f *FieldMeta) Value(v reflect.Value) reflect.Value {
+	field, err := v.FieldByIndexErr(f.Index)
+	if err != nil {
+		return reflect.Zero(f.Type)
+	}
+	return field
+}
+
+// Settable returns the field of v, an addressable entity struct value, for
+// writing, allocating nil embedded pointers on the way.
+func (f *FieldMeta) Settable(v reflect.Value) reflect.Value {
+	for i, x := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// IsRelation reports whether the field holds related entities: a struct, or a
+// pointer to or slice of structs, that GORM stores as an association rather
+// than in a column.
+func (f *FieldMeta) IsRelation() bool {
+	return f.Column != "" && f.Aggregate == nil && !f.IsColumn() && structType(f.Type) != nil
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
 // GetAggregateByName returns an aggregate by its name.
 func (em *EntityMeta) GetAggregateByName(name string) *AggregateMeta {
 	for _, a := range em.Aggregates {
@@ -2,7 +2,9 @@ package meta
 
 import (
 	"reflect"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 // registry is a global cache of parsed entity metadata.
@@ -30,6 +32,7 @@ func Parse(entity any) (*EntityMeta, error) {
 		Fields:     make([]*FieldMeta, 0),
 		Nested:     make([]*NestedMeta, 0),
 		Aggregates: make([]*AggregateMeta, 0),
+		Relations:  make([]*RelationMeta, 0),
 	}
 
 	// Extract table name from gorm tag if present
@@ -40,10 +43,119 @@ func Parse(entity any) (*EntityMeta, error) {
 	}
 
 	// Parse fields
+	parseFields(meta, t, nil)
+
+	// Like GORM, fall back to a field named ID as the primary key
+	if meta.PKField == nil {
+		if f := meta.GetFieldByName("ID"); f != nil {
+			f.IsPK = true
+			meta.PKField = f
+			meta.PKFields = []*FieldMeta{f}
+		}
+	}
+
+	// Cache it before resolving relations, so that cyclic relations terminate
+	registry[key] = meta
+
+	if err := parseRelations(meta); err != nil {
+		delete(registry, key)
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// parseRelations records the fk:, m2m: and list relations of meta and
+// parses the related entities.
+func parseRelations(meta *EntityMeta) error {
+	for _, f := range meta.Fields {
+		var rel *RelationMeta
+		switch {
+		case f.FK != nil:
+			field := relationField(meta, f.FK.TableName)
+			if field == nil {
+				// A foreign key without a relation field cannot be expanded
+				continue
+			}
+			rel = &RelationMeta{Kind: "fk", Field: field, Key: f, Default: f.Include || field.Include}
+		case f.M2M != nil:
+			rel = &RelationMeta{Kind: "m2m", Field: f, Default: f.Include}
+		case f.List:
+			rel = &RelationMeta{Kind: "list", Field: f, Default: f.Include}
+		default:
+			continue
+		}
+
+		t := structType(rel.Field.Type)
+		if t == nil {
+			// Only structs, pointers and slices of structs can be preloaded
+			continue
+		}
+
+		entity, err := Parse(reflect.New(t).Interface())
+		if err != nil {
+			return err
+		}
+
+		rel.Name = rel.Field.Name
+		rel.Param = toSnakeCase(rel.Field.Name)
+		rel.Entity = entity
+		meta.Relations = append(meta.Relations, rel)
+	}
+
+	return nil
+}
+
+// relationField returns the field holding the entity referenced by fk:<name>:
+// a struct field called name, or else the first one whose type is called name.
+func relationField(meta *EntityMeta, name string) *FieldMeta {
+	if f := meta.GetFieldByName(name); f != nil && structType(f.Type) != nil && !f.IsColumn() {
+		return f
+	}
+	for _, f := range meta.Fields {
+		if t := structType(f.Type); t != nil && t.Name() == name && !f.IsColumn() {
+			return f
+		}
+	}
+	return nil
+}
+
+// structType returns the struct type of a struct, pointer or slice field, or nil.
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// parseFields adds the fields of t to meta. Embedded structs and pointers to
+// structs, such as gorm.Model, are flattened, with index paths relative to the entity.
+func parseFields(meta *EntityMeta, t reflect.Type, index []int) {
 	for i := 0; i < t.NumField(); i++ {
 		sf := t.Field(i)
+		sf.Index = append(slices.Clone(index), sf.Index...)
 
 		// Skip unexported fields
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		// Flatten embedded structs
+		if embedded := embeddedStruct(sf); embedded != nil {
+			meta.Nested = append(meta.Nested, &NestedMeta{
+				Name:  sf.Name,
+				Type:  sf.Type,
+				Index: sf.Index,
+			})
+			parseFields(meta, embedded, sf.Index)
+			continue
+		}
 		if sf.PkgPath != "" {
 			continue
 		}
@@ -54,15 +166,34 @@ func Parse(entity any) (*EntityMeta, error) {
 
 			// Track primary key
 			if fm.IsPK {
-				meta.PKField = fm
+				if meta.PKField == nil {
+					meta.PKField = fm
+				}
+				meta.PKFields = append(meta.PKFields, fm)
+			}
+
+			// Track aggregates
+			if fm.Aggregate != nil {
+				meta.Aggregates = append(meta.Aggregates, fm.Aggregate)
 			}
 		}
 	}
+}
 
-	// Cache it
-	registry[key] = meta
-
-	return meta, nil
+// embeddedStruct returns the struct type of an untagged embedded struct or
+// pointer to struct, or nil for other fields.
+func embeddedStruct(sf reflect.StructField) reflect.Type {
+	if !sf.Anonymous || sf.Tag.Get("go-blar") != "" {
+		return nil
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
 }
 
 // parseField extracts metadata from a struct field.
@@ -75,10 +206,12 @@ func parseField(sf reflect.StructField) *FieldMeta {
 	}
 
 	fm := &FieldMeta{
-		Name:  sf.Name,
-		Type:  sf.Type,
-		Index: sf.Index,
+		Name:   sf.Name,
+		Column: parseGormColumn(sf.Name, gormTag),
+		Type:   sf.Type,
+		Index:  sf.Index,
 	}
+	fm.JSONName, fm.OmitEmpty = parseJSONTag(sf.Name, sf.Tag.Get("json"))
 
 	// Parse go-blar tags
 	if blarTag != "" {
@@ -97,42 +230,131 @@ func parseField(sf reflect.StructField) *FieldMeta {
 				fm.Hidden = true
 			case part == "readonly":
 				fm.ReadOnly = true
+			case part == "writeonly":
+				fm.WriteOnly = true
+			case strings.HasPrefix(part, "transform:"):
+				fm.Transform = strings.TrimPrefix(part, "transform:")
+			case part == "include":
+				fm.Include = true
+			case strings.HasPrefix(part, "validate:"):
+				fm.Validate = parseRules(strings.TrimPrefix(part, "validate:"))
 			case strings.HasPrefix(part, "fk:"):
 				fkTable := strings.TrimPrefix(part, "fk:")
 				fm.FK = &ForeignKey{TableName: fkTable}
 			case strings.HasPrefix(part, "m2m:"):
 				m2mTable := strings.TrimPrefix(part, "m2m:")
 				fm.M2M = &ManyToMany{TableName: m2mTable}
-			case strings.HasPrefix(part, "count:"):
-				fieldPath := strings.TrimPrefix(part, "count:")
-				meta := &AggregateMeta{
-					Name:  fm.Name,
-					Type:  "count",
-					Field: fieldPath,
-				}
-				// This will be added to EntityMeta later
-				_ = meta
-			case strings.HasPrefix(part, "sum:"):
-				fieldPath := strings.TrimPrefix(part, "sum:")
-				meta := &AggregateMeta{
-					Name:  fm.Name,
-					Type:  "sum",
-					Field: fieldPath,
-				}
-				// This will be added to EntityMeta later
-				_ = meta
+			case isAggregate(part):
+				fm.Aggregate = parseAggregate(fm.Name, part)
 			}
 		}
 	}
 
-	// Parse gorm tags for primary key detection
-	if strings.Contains(gormTag, "primaryKey") {
+	// Parse gorm tags for primary key detection (gorm tag keys are case-insensitive)
+	if strings.Contains(strings.ToLower(gormTag), "primarykey") {
 		fm.IsPK = true
 	}
 
 	return fm
 }
 
+// parseRules parses comma-separated validation rules such as
+// "required,min=3,oneof=red green". A regex= rule takes the rest of the
+// list, so that the pattern may contain commas; it must come last.
+func parseRules(list string) []Rule {
+	rules := make([]Rule, 0)
+	for list != "" {
+		var item string
+		if strings.HasPrefix(list, "regex=") {
+			item, list = list, ""
+		} else {
+			item, list, _ = strings.Cut(list, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		if name != "" {
+			rules = append(rules, Rule{Name: name, Param: param})
+		}
+		list = strings.TrimSpace(list)
+	}
+	return rules
+}
+
+// aggregateTypes lists the supported aggregate directives.
+var aggregateTypes = []string{"count", "countDistinct", "sum", "avg", "min", "max"}
+
+// isAggregate reports whether a tag part is an aggregate directive such as "sum:Items.Price".
+func isAggregate(part string) bool {
+	aggType, _, ok := strings.Cut(part, ":")
+	return ok && slices.Contains(aggregateTypes, aggType)
+}
+
+// parseAggregate parses an aggregate directive of the form
+// "<type>:<path or expression>[ where <condition>]".
+func parseAggregate(name, part string) *AggregateMeta {
+	aggType, fieldPath, _ := strings.Cut(part, ":")
+
+	filter := ""
+	if i := strings.Index(strings.ToLower(fieldPath), " where "); i != -1 {
+		filter = strings.TrimSpace(fieldPath[i+len(" where "):])
+		fieldPath = fieldPath[:i]
+	}
+	fieldPath = strings.TrimSpace(fieldPath)
+
+	return &AggregateMeta{
+		Name:     name,
+		Type:     aggType,
+		Relation: aggregateRelation(fieldPath),
+		Field:    fieldPath,
+		Filter:   filter,
+	}
+}
+
+// aggregateRelation returns the relation an aggregate path refers to,
+// i.e. its leading identifier ("Items" for "Items.Price*Items.Quantity").
+func aggregateRelation(fieldPath string) string {
+	fieldPath = strings.TrimLeft(fieldPath, " (")
+	end := strings.IndexFunc(fieldPath, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if end == -1 {
+		return fieldPath
+	}
+	return fieldPath[:end]
+}
+
+// parseGormColumn returns the column a field is stored in: the gorm
+// "column:" setting, or the snake_case field name. Fields tagged gorm:"-"
+// are not stored and have no column.
+func parseGormColumn(fieldName, tag string) string {
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "-" || strings.EqualFold(part, "-:all") {
+			return ""
+		}
+		if key, value, ok := strings.Cut(part, ":"); ok && strings.EqualFold(key, "column") {
+			return value
+		}
+	}
+
+	return toSnakeCase(fieldName)
+}
+
+// parseJSONTag returns the JSON key of a field and whether it is omitempty,
+// following encoding/json. Fields tagged json:"-" have no key.
+func parseJSONTag(fieldName, tag string) (string, bool) {
+	if tag == "-" {
+		return "", false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = fieldName
+	}
+
+	return name, slices.Contains(strings.Split(opts, ","), "omitempty")
+}
+
 // parseGormTag extracts the table name from a gorm tag.
 func parseGormTag(tag string) string {
 	if tag == "" {
@@ -179,6 +401,34 @@ func toSnakeCase(s string) string {
 	return strings.ToLower(result.String())
 }
 
+// ResolveForeignKeys sets the entity each fk: field of entities refers to,
+// among entities: the one called by the tag, such as the User of fk:User, or
+// else the first one stored in the table it names. Fields of tags naming none
+// of them are left unresolved. Call it once all entities are parsed, before
+// they are used to serve requests.
+func ResolveForeignKeys(entities []*EntityMeta) {
+	find := func(name string) *EntityMeta {
+		var byTable *EntityMeta
+		for _, meta := range entities {
+			if meta.Name == name {
+				return meta
+			}
+			if meta.TableName == name && byTable == nil {
+				byTable = meta
+			}
+		}
+		return byTable
+	}
+
+	for _, meta := range entities {
+		for _, f := range meta.Fields {
+			if f.FK != nil {
+				f.FK.Entity = find(f.FK.TableName)
+			}
+		}
+	}
+}
+
 // ClearRegistry clears the metadata cache (useful for testing).
 func ClearRegistry() {
 	registry = make(map[string]*EntityMeta)
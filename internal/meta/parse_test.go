@@ -3,6 +3,7 @@ package meta
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 // TestParseBasicStruct tests parsing a basic struct.
@@ -209,3 +210,354 @@ func TestGetFieldByName(t *testing.T) {
 		t.Fatal("expected nil for non-existent field")
 	}
 }
+
+func TestParseAggregateTags(t *testing.T) {
+	type Item struct {
+		ID       uint
+		Price    float64
+		Quantity int
+	}
+
+	type Order struct {
+		ID        uint
+		Items     []Item  `go-blar:"list"`
+		ItemCount int     `go-blar:"count:Items"`
+		ItemSum   float64 `go-blar:"sum:Items.Price*Items.Quantity"`
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meta.Aggregates) != 2 {
+		t.Fatalf("expected 2 aggregates, got %d", len(meta.Aggregates))
+	}
+
+	count := meta.GetAggregateByName("ItemCount")
+	if count == nil || count.Type != "count" || count.Relation != "Items" || count.Field != "Items" {
+		t.Fatalf("unexpected count aggregate: %+v", count)
+	}
+
+	sum := meta.GetAggregateByName("ItemSum")
+	if sum == nil || sum.Type != "sum" || sum.Relation != "Items" || sum.Field != "Items.Price*Items.Quantity" {
+		t.Fatalf("unexpected sum aggregate: %+v", sum)
+	}
+
+	if meta.GetFieldByName("ItemSum").Aggregate != sum {
+		t.Fatal("expected field to reference its aggregate")
+	}
+}
+
+func TestParseAggregateFilter(t *testing.T) {
+	type Item struct {
+		ID       uint
+		Quantity int
+	}
+
+	type Order struct {
+		ID       uint
+		Items    []Item
+		InStock  int     `go-blar:"count:Items where Quantity>0"`
+		AvgQty   float64 `go-blar:"avg:Items.Quantity"`
+		Distinct int     `go-blar:"countDistinct:Items.Quantity WHERE Quantity > 1"`
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inStock := meta.GetAggregateByName("InStock")
+	if inStock == nil || inStock.Type != "count" || inStock.Field != "Items" || inStock.Filter != "Quantity>0" {
+		t.Fatalf("unexpected filtered aggregate: %+v", inStock)
+	}
+
+	avg := meta.GetAggregateByName("AvgQty")
+	if avg == nil || avg.Type != "avg" || avg.Filter != "" {
+		t.Fatalf("unexpected avg aggregate: %+v", avg)
+	}
+
+	distinct := meta.GetAggregateByName("Distinct")
+	if distinct == nil || distinct.Type != "countDistinct" || distinct.Field != "Items.Quantity" || distinct.Filter != "Quantity > 1" {
+		t.Fatalf("unexpected countDistinct aggregate: %+v", distinct)
+	}
+}
+
+func TestParseEmbeddedStruct(t *testing.T) {
+	type Base struct {
+		ID        uint `gorm:"primarykey"`
+		CreatedAt time.Time
+	}
+
+	type Entity struct {
+		Base
+		Name     string
+		Internal string `gorm:"column:internal_name"`
+		Computed int    `gorm:"-"`
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meta.Nested) != 1 || meta.Nested[0].Name != "Base" {
+		t.Fatalf("expected embedded Base to be recorded, got %+v", meta.Nested)
+	}
+
+	if meta.PKField == nil || meta.PKField.Name != "ID" {
+		t.Fatal("expected ID from embedded struct to be the primary key")
+	}
+
+	if !reflect.DeepEqual(meta.PKField.Index, []int{0, 0}) {
+		t.Fatalf("expected index [0 0], got %v", meta.PKField.Index)
+	}
+
+	if f := meta.GetFieldByColumn("created_at"); f == nil || !f.IsColumn() {
+		t.Fatal("expected created_at column from embedded struct")
+	}
+
+	if f := meta.GetFieldByColumn("internal_name"); f == nil || f.Name != "Internal" {
+		t.Fatal("expected column name from gorm tag")
+	}
+
+	if f := meta.GetFieldByName("Computed"); f == nil || f.IsColumn() {
+		t.Fatal("expected gorm:\"-\" field not to be a column")
+	}
+}
+
+func TestParseEmbeddedPointer(t *testing.T) {
+	type Base struct {
+		Secret string `go-blar:"hidden"`
+	}
+
+	type Entity struct {
+		*Base
+		ID uint
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := meta.GetFieldByName("Secret")
+	if secret == nil || !secret.Hidden || !reflect.DeepEqual(secret.Index, []int{0, 0}) {
+		t.Fatalf("expected hidden Secret from embedded pointer, got %+v", secret)
+	}
+	if meta.GetFieldByName("Base") != nil {
+		t.Fatal("expected embedded pointer to be flattened")
+	}
+
+	// Nil embedded pointers read as zero values and are allocated on write
+	var entity Entity
+	if value := secret.Value(reflect.ValueOf(entity)); value.String() != "" {
+		t.Fatalf("expected zero value, got %v", value)
+	}
+	secret.Settable(reflect.ValueOf(&entity).Elem()).SetString("s")
+	if entity.Base == nil || entity.Secret != "s" {
+		t.Fatalf("expected embedded pointer to be allocated, got %+v", entity)
+	}
+}
+
+func TestParseJSONTags(t *testing.T) {
+	type Entity struct {
+		ID       uint
+		Name     string `json:"name"`
+		Note     string `json:"note,omitempty"`
+		Internal string `json:"-"`
+		Plain    string
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field     string
+		jsonName  string
+		omitEmpty bool
+	}{
+		{"Name", "name", false},
+		{"Note", "note", true},
+		{"Internal", "", false},
+		{"Plain", "Plain", false},
+	}
+	for _, tt := range tests {
+		f := meta.GetFieldByName(tt.field)
+		if f.JSONName != tt.jsonName || f.OmitEmpty != tt.omitEmpty {
+			t.Errorf("%s: expected %q omitempty=%v, got %q omitempty=%v", tt.field, tt.jsonName, tt.omitEmpty, f.JSONName, f.OmitEmpty)
+		}
+	}
+
+	if f := meta.GetFieldByParam("plain"); f == nil || f.Name != "Plain" {
+		t.Fatal("expected field to be found by column name")
+	}
+}
+
+type relUser struct {
+	ID       uint
+	Name     string
+	Products []relProduct `go-blar:"list" gorm:"foreignKey:UserID"`
+}
+
+type relTag struct {
+	ID   uint
+	Name string
+}
+
+type relProduct struct {
+	ID     uint
+	UserID uint     `go-blar:"fk:relUser;include"`
+	Owner  *relUser `gorm:"foreignKey:UserID"`
+	Tags   []relTag `go-blar:"m2m:product_tags"`
+	LotID  uint     `go-blar:"fk:Lot"`
+}
+
+func TestParseRelations(t *testing.T) {
+	ClearRegistry()
+	meta, err := Parse(&relProduct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meta.Relations) != 2 {
+		t.Fatalf("expected 2 relations (fk without relation field skipped), got %d", len(meta.Relations))
+	}
+
+	owner := meta.GetRelationByParam("owner")
+	if owner == nil || owner.Kind != "fk" || owner.Key.Name != "UserID" || !owner.Default {
+		t.Fatalf("expected default fk relation Owner keyed by UserID, got %+v", owner)
+	}
+
+	tags := meta.GetRelationByParam("tags")
+	if tags == nil || tags.Kind != "m2m" || tags.Default || tags.Entity.Name != "relTag" {
+		t.Fatalf("expected m2m relation Tags, got %+v", tags)
+	}
+
+	// Cyclic relations resolve to the cached metadata
+	products := owner.Entity.GetRelationByParam("products")
+	if products == nil || products.Kind != "list" || products.Entity != meta {
+		t.Fatalf("expected list relation back to relProduct, got %+v", products)
+	}
+}
+
+func TestParseWriteOnlyTags(t *testing.T) {
+	type Entity struct {
+		ID       uint
+		Password string `go-blar:"writeonly;transform:hash"`
+		Secret   string `go-blar:"hidden"`
+		Name     string
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := meta.GetFieldByName("Password")
+	if !password.WriteOnly || password.Transform != "hash" || password.Readable() {
+		t.Fatalf("expected unreadable write-only Password with hash transform, got %+v", password)
+	}
+
+	if meta.GetFieldByName("Secret").Readable() || !meta.GetFieldByName("Name").Readable() {
+		t.Fatal("expected only hidden and write-only fields to be unreadable")
+	}
+}
+
+func TestParseCompositePrimaryKey(t *testing.T) {
+	type Entity struct {
+		ProductID uint `gorm:"primaryKey"`
+		TagID     uint `gorm:"primaryKey"`
+		Note      string
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meta.PKFields) != 2 || meta.PKFields[0].Name != "ProductID" || meta.PKFields[1].Name != "TagID" {
+		t.Fatalf("expected ProductID and TagID primary keys, got %v", meta.PKFields)
+	}
+	if meta.PKField != meta.PKFields[0] {
+		t.Fatal("expected PKField to be the first primary key")
+	}
+}
+
+func TestParseValidationRules(t *testing.T) {
+	type Entity struct {
+		ID    uint
+		Name  string `go-blar:"readonly;validate:required, min=3,max=50"`
+		Color string `go-blar:"validate:oneof=red green blue"`
+		Code  string `go-blar:"validate:len=4,regex=^[A-Z]{2},[0-9]$"`
+		Note  string
+	}
+
+	ClearRegistry()
+	meta, err := Parse(&Entity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]Rule{
+		"Name":  {{Name: "required"}, {Name: "min", Param: "3"}, {Name: "max", Param: "50"}},
+		"Color": {{Name: "oneof", Param: "red green blue"}},
+		"Code":  {{Name: "len", Param: "4"}, {Name: "regex", Param: "^[A-Z]{2},[0-9]$"}},
+	}
+	for name, want := range tests {
+		if got := meta.GetFieldByName(name).Validate; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected rules %v, got %v", name, want, got)
+		}
+	}
+
+	if !meta.GetFieldByName("Name").ReadOnly {
+		t.Fatal("expected other tags to be parsed alongside validate")
+	}
+	if rules := meta.GetFieldByName("Note").Validate; len(rules) != 0 {
+		t.Fatalf("expected no rules, got %v", rules)
+	}
+}
+
+func TestReferencedEntity(t *testing.T) {
+	type Owner struct {
+		ID   uint
+		Name string
+	}
+	type Pet struct {
+		ID       uint
+		OwnerID  uint  `go-blar:"fk:Owner"`
+		KeeperID *uint `go-blar:"fk:owners"`
+		VetID    uint  `go-blar:"fk:Vet"`
+		Name     string
+	}
+
+	ClearRegistry()
+	owner, err := Parse(&Owner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pet, err := Parse(&Pet{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags name entities or their tables; Vet is unknown
+	ResolveForeignKeys([]*EntityMeta{owner, pet})
+
+	tests := map[string]*EntityMeta{"OwnerID": owner, "KeeperID": owner, "VetID": nil, "Name": nil}
+	for name, want := range tests {
+		if got := pet.ReferencedEntity(pet.GetFieldByName(name)); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed, tampered with,
+// or were issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorPage is the window of results requested with ?cursor=&limit=.
+type CursorPage struct {
+	Size   int
+	Cursor string // empty for the first page
+}
+
+// cursorPayload is the signed content of a cursor: the sort order it was
+// issued for and the sort column values of the last row returned.
+type cursorPayload struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+}
+
+// ParseCursorPage parses keyset pagination parameters. Missing sizes use
+// defaultSize, or DefaultPageSize if it is below 1, and sizes above maxSize are
+// capped unless maxSize is 0. Offset parameters are rejected.
+func ParseCursorPage(values url.Values, defaultSize, maxSize int) (CursorPage, error) {
+	if defaultSize < 1 {
+		defaultSize = DefaultPageSize
+	}
+	for _, name := range []string{"page", "per_page", "offset"} {
+		if values.Has(name) {
+			return CursorPage{}, fmt.Errorf("%s is not supported with cursor pagination; use cursor and limit", name)
+		}
+	}
+
+	size, err := intParam(values, "limit", defaultSize, 1)
+	if err != nil {
+		return CursorPage{}, err
+	}
+	if maxSize > 0 && size > maxSize {
+		size = maxSize
+	}
+
+	return CursorPage{Size: size, Cursor: values.Get("cursor")}, nil
+}
+
+// EncodeCursor returns a signed cursor pointing after row, an entity struct
+// value, for the given sort order.
+func EncodeCursor(secret []byte, sorts []Sort, row reflect.Value) (string, error) {
+	payload := cursorPayload{Sort: sortKey(sorts), Values: make([]*string, 0, len(sorts))}
+	for _, s := range sorts {
+		value, err := formatValue(s.Field.Value(row))
+		if err != nil {
+			return "", err
+		}
+		payload.Values = append(payload.Values, value)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(sign(secret, data)), nil
+}
+
+// DecodeCursor verifies a cursor and returns its sort column values,
+// converted to the types of the sort fields.
+func DecodeCursor(secret []byte, token string, sorts []Sort) ([]any, error) {
+	encoding := base64.RawURLEncoding
+
+	data64, sig64, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := encoding.DecodeString(data64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := encoding.DecodeString(sig64)
+	if err != nil || !hmac.Equal(sig, sign(secret, data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Sort != sortKey(sorts) || len(payload.Values) != len(sorts) {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+
+	values := make([]any, len(sorts))
+	for i, s := range sorts {
+		if payload.Values[i] == nil {
+			continue
+		}
+		v, err := ConvertValue(s.Field.Type, *payload.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// ApplyCursor restricts the query to rows after the cursor values in sort order:
+// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., with < for descending columns.
+// NULL values of nullable columns are placed where the dialect sorts them:
+// before other values in ascending order, or after them on PostgreSQL.
+func ApplyCursor(db *gorm.DB, sorts []Sort, values []any) *gorm.DB {
+	nullsLast := db.Dialector.Name() == "postgres"
+	branches := make([]clause.Expression, 0, len(sorts))
+
+	for i, s := range sorts {
+		column := sortColumn(s)
+		nullsAfter := nullsLast != s.Desc
+
+		// Rows after a NULL are the non-NULL values, if those come after it
+		var after clause.Expression
+		switch {
+		case values[i] == nil && nullsAfter:
+			continue
+		case values[i] == nil:
+			after = clause.Neq{Column: column, Value: nil}
+		case s.Desc:
+			after = clause.Lt{Column: column, Value: values[i]}
+		default:
+			after = clause.Gt{Column: column, Value: values[i]}
+		}
+		if values[i] != nil && nullsAfter && s.Field.Nullable() {
+			after = clause.Or(after, clause.Eq{Column: column, Value: nil})
+		}
+
+		exprs := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			// Eq renders IS NULL for nil values
+			exprs = append(exprs, clause.Eq{Column: sortColumn(sorts[j]), Value: values[j]})
+		}
+		branches = append(branches, clause.And(append(exprs, after)...))
+	}
+
+	if len(branches) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where(clause.Or(branches...))
+}
+
+// CursorLink returns u with its cursor parameters set to the given cursor.
+func (p CursorPage) CursorLink(u *url.URL, cursor string) string {
+	values := u.Query()
+	values.Set("cursor", cursor)
+	values.Set("limit", strconv.Itoa(p.Size))
+
+	link := *u
+	link.RawQuery = values.Encode()
+	return link.String()
+}
+
+// sortKey returns a canonical form of the sort order, e.g. "-created_at,id".
+func sortKey(sorts []Sort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, s := range sorts {
+		if s.Desc {
+			parts = append(parts, "-"+s.Field.Column)
+		} else {
+			parts = append(parts, s.Field.Column)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortColumn returns the qualified column of a sort.
+func sortColumn(s Sort) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: s.Field.Column}
+}
+
+// sign returns the HMAC-SHA256 signature of data.
+func sign(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// formatValue formats a sort column value as a string that ConvertValue can parse back.
+// Nil pointers and NULL values format as nil.
+func formatValue(v reflect.Value) (*string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	value := v.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		if dv == nil {
+			return nil, nil
+		}
+		value = dv
+	}
+
+	var s string
+	if t, ok := value.(time.Time); ok {
+		s = t.Format(time.RFC3339Nano)
+	} else {
+		s = fmt.Sprint(value)
+	}
+	return &s, nil
+}
@@ -0,0 +1,172 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	db, entityMeta := setupTestDB(t)
+	secret := []byte("secret")
+
+	sorts, err := ParseSort(url.Values{"sort": {"-price"}}, entityMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first []Product
+	if err := ApplySort(db, sorts).Limit(1).Find(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := EncodeCursor(secret, sorts, reflect.ValueOf(first[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := DecodeCursor(secret, cursor, sorts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var rest []Product
+	if err := ApplyCursor(ApplySort(db, sorts), sorts, values).Find(&rest).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"phone", "phone case"}
+	if len(rest) != len(expected) {
+		t.Fatalf("expected %d products after cursor, got %d", len(expected), len(rest))
+	}
+	for i, name := range expected {
+		if rest[i].Name != name {
+			t.Errorf("expected %s at %d, got %s", name, i, rest[i].Name)
+		}
+	}
+}
+
+type Task struct {
+	ID  uint `gorm:"primaryKey"`
+	Due *int
+}
+
+func TestCursorNullValues(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Task{}); err != nil {
+		t.Fatal(err)
+	}
+	one, two := 1, 2
+	if err := db.Create(&[]Task{{Due: nil}, {Due: &one}, {Due: nil}, {Due: &two}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+
+	// SQLite sorts NULLs first in ascending order
+	for sort, expected := range map[string][]uint{"due": {1, 3, 2, 4}, "-due": {4, 2, 1, 3}} {
+		sorts, err := ParseSort(url.Values{"sort": {sort}}, entityMeta)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]uint, 0)
+		var cursor string
+		for len(ids) <= len(expected) {
+			find := ApplySort(db, sorts)
+			if cursor != "" {
+				values, err := DecodeCursor(secret, cursor, sorts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				find = ApplyCursor(find, sorts, values)
+			}
+
+			var page []Task
+			if err := find.Limit(1).Find(&page).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			ids = append(ids, page[0].ID)
+
+			if cursor, err = EncodeCursor(secret, sorts, reflect.ValueOf(page[0])); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("sort=%s: expected %v, got %v", sort, expected, ids)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	db, entityMeta := setupTestDB(t)
+	secret := []byte("secret")
+
+	sorts, err := ParseSort(url.Values{"sort": {"name"}}, entityMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var products []Product
+	if err := db.Limit(1).Find(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := EncodeCursor(secret, sorts, reflect.ValueOf(products[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSorts, err := ParseSort(url.Values{"sort": {"-name"}}, entityMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		secret []byte
+		cursor string
+		sorts  []Sort
+	}{
+		"malformed":  {secret, "not-a-cursor", sorts},
+		"tampered":   {secret, "x" + cursor, sorts},
+		"wrong key":  {[]byte("other"), cursor, sorts},
+		"other sort": {secret, cursor, otherSorts},
+	}
+
+	for name, tt := range tests {
+		if _, err := DecodeCursor(tt.secret, tt.cursor, tt.sorts); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
+
+func TestParseCursorPage(t *testing.T) {
+	page, err := ParseCursorPage(url.Values{"limit": {"500"}, "cursor": {"abc"}}, 20, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Size != 100 || page.Cursor != "abc" {
+		t.Fatalf("unexpected cursor page: %+v", page)
+	}
+
+	if _, err := ParseCursorPage(url.Values{"page": {"2"}}, 20, 100); err == nil {
+		t.Fatal("expected error for page parameter in cursor mode")
+	}
+}
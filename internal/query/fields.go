@@ -0,0 +1,79 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ParseFields parses a sparse fieldset such as ?fields=id,name,price.
+// Fields are named by column (or snake_case name for aggregates) and must be visible.
+// The primary key fields are always included. It returns nil when no fieldset is requested.
+func ParseFields(values url.Values, entityMeta *meta.EntityMeta) ([]*meta.FieldMeta, error) {
+	if !values.Has("fields") {
+		return nil, nil
+	}
+
+	fields := slices.Clone(entityMeta.PKFields)
+
+	for _, raw := range values["fields"] {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			field := entityMeta.GetFieldByParam(name)
+			if field == nil || !field.Readable() || (!field.IsColumn() && field.Aggregate == nil) {
+				return nil, fmt.Errorf("unknown field %q", name)
+			}
+
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// ApplyFields narrows the query's SELECT to the columns of fields plus any
+// extra fields the query needs, such as sort columns. Nil fields select everything.
+func ApplyFields(db *gorm.DB, fields []*meta.FieldMeta, extra ...*meta.FieldMeta) *gorm.DB {
+	if fields == nil {
+		return db
+	}
+
+	columns := make([]clause.Column, 0, len(fields)+len(extra))
+	seen := make(map[string]bool)
+	for _, f := range slices.Concat(fields, extra) {
+		if !f.IsColumn() || seen[f.Column] {
+			continue
+		}
+		seen[f.Column] = true
+		columns = append(columns, clause.Column{Table: clause.CurrentTable, Name: f.Column})
+	}
+
+	return db.Clauses(clause.Select{Columns: columns})
+}
+
+// Aggregates returns the aggregates to compute for a fieldset:
+// all of them without a fieldset, otherwise only the requested ones.
+func Aggregates(entityMeta *meta.EntityMeta, fields []*meta.FieldMeta) []*meta.AggregateMeta {
+	if fields == nil {
+		return entityMeta.Aggregates
+	}
+
+	aggregates := make([]*meta.AggregateMeta, 0)
+	for _, f := range fields {
+		if f.Aggregate != nil {
+			aggregates = append(aggregates, f.Aggregate)
+		}
+	}
+	return aggregates
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestParseFields(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	fields, err := ParseFields(url.Values{"fields": {"name,price"}}, entityMeta)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "ID,Name,Price" {
+		t.Fatalf("expected ID,Name,Price, got %v", names)
+	}
+
+	fields, err = ParseFields(url.Values{}, entityMeta)
+	if err != nil || fields != nil {
+		t.Fatalf("expected nil fieldset without parameter, got %v, %v", fields, err)
+	}
+}
+
+func TestParseFieldsInvalid(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	for _, q := range []string{"fields=unknown", "fields=name,secret", "fields=model"} {
+		values, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseFields(values, entityMeta); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
+
+func TestApplyFields(t *testing.T) {
+	db, entityMeta := setupTestDB(t)
+
+	fields, err := ParseFields(url.Values{"fields": {"name"}}, entityMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt := ApplyFields(db.Session(&gorm.Session{DryRun: true}), fields, entityMeta.GetFieldByColumn("price")).
+		Find(&[]Product{}).Statement
+	sql := stmt.SQL.String()
+
+	if !strings.HasPrefix(sql, "SELECT `products`.`id`,`products`.`name`,`products`.`price` FROM") {
+		t.Fatalf("unexpected SQL: %s", sql)
+	}
+
+	var products []Product
+	if err := ApplyFields(db, fields).Order("id").Find(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+	if products[0].ID == 0 || products[0].Name != "phone" || products[0].Price != 0 {
+		t.Fatalf("expected only id and name to be loaded, got %+v", products[0])
+	}
+}
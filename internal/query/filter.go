@@ -0,0 +1,187 @@
+package query
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Filter is a single condition parsed from the query string, e.g. ?price[gte]=10.
+type Filter struct {
+	Field    *meta.FieldMeta
+	Operator string
+	Value    any // converted to the field's type; []any for "in", bool for "null"
+}
+
+// reserved are query parameters that control listing rather than filter on fields.
+var reserved = map[string]bool{
+	"page":     true,
+	"per_page": true,
+	"limit":    true,
+	"offset":   true,
+	"sort":     true,
+	"cursor":   true,
+	"fields":   true,
+	"include":  true,
+}
+
+// operators lists the supported filter operators.
+var operators = []string{"eq", "ne", "gt", "gte", "lt", "lte", "in", "like", "null"}
+
+// ParseFilters parses filter parameters such as ?name=foo, ?price[gte]=10,
+// ?status[in]=a,b, ?name[like]=phone% and ?deleted_at[null]=true.
+// Parameters name fields by column, and must refer to visible column fields.
+func ParseFilters(values url.Values, entityMeta *meta.EntityMeta) ([]Filter, error) {
+	filters := make([]Filter, 0)
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		name, op, err := splitKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if reserved[name] && op == "" {
+			continue
+		}
+		if op == "" {
+			op = "eq"
+		}
+
+		field := entityMeta.GetFieldByColumn(name)
+		if field == nil || !field.Readable() || !field.IsColumn() {
+			return nil, fmt.Errorf("unknown filter field %q", name)
+		}
+
+		for _, raw := range values[key] {
+			value, err := filterValue(field, op, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+			filters = append(filters, Filter{Field: field, Operator: op, Value: value})
+		}
+	}
+
+	return filters, nil
+}
+
+// ApplyFilters adds the filters to the query as parameterized where clauses.
+func ApplyFilters(db *gorm.DB, filters []Filter) *gorm.DB {
+	for _, f := range filters {
+		db = db.Where(f.expression())
+	}
+	return db
+}
+
+// expression returns the where clause for the filter.
+func (f Filter) expression() clause.Expression {
+	column := clause.Column{Table: clause.CurrentTable, Name: f.Field.Column}
+
+	switch f.Operator {
+	case "ne":
+		return clause.Neq{Column: column, Value: f.Value}
+	case "gt":
+		return clause.Gt{Column: column, Value: f.Value}
+	case "gte":
+		return clause.Gte{Column: column, Value: f.Value}
+	case "lt":
+		return clause.Lt{Column: column, Value: f.Value}
+	case "lte":
+		return clause.Lte{Column: column, Value: f.Value}
+	case "in":
+		return clause.IN{Column: column, Values: f.Value.([]any)}
+	case "like":
+		return clause.Like{Column: column, Value: f.Value}
+	case "null":
+		if f.Value.(bool) {
+			return clause.Eq{Column: column, Value: nil}
+		}
+		return clause.Neq{Column: column, Value: nil}
+	default:
+		return clause.Eq{Column: column, Value: f.Value}
+	}
+}
+
+// splitKey splits a parameter such as "price[gte]" into its field and operator.
+func splitKey(key string) (string, string, error) {
+	name, rest, ok := strings.Cut(key, "[")
+	if !ok {
+		return key, "", nil
+	}
+
+	op, ok := strings.CutSuffix(rest, "]")
+	if !ok || !slices.Contains(operators, op) {
+		return "", "", fmt.Errorf("invalid filter operator in %q", key)
+	}
+
+	return name, op, nil
+}
+
+// filterValue converts a raw parameter value for the given operator.
+func filterValue(field *meta.FieldMeta, op, raw string) (any, error) {
+	switch op {
+	case "null":
+		return strconv.ParseBool(raw)
+	case "like":
+		if kind := baseType(field.Type).Kind(); kind != reflect.String {
+			return nil, fmt.Errorf("like requires a string field")
+		}
+		return raw, nil
+	case "in":
+		parts := strings.Split(raw, ",")
+		values := make([]any, 0, len(parts))
+		for _, part := range parts {
+			v, err := ConvertValue(field.Type, part)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	default:
+		return ConvertValue(field.Type, raw)
+	}
+}
+
+// ConvertValue converts a string from a URL to a value of type t.
+// Types without a string conversion, such as custom scanners, are passed through as strings.
+func ConvertValue(t reflect.Type, raw string) (any, error) {
+	t = baseType(t)
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, t.Bits())
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		if v, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return v, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+
+	return raw, nil
+}
+
+// baseType dereferences pointer types.
+func baseType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
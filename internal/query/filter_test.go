@@ -0,0 +1,120 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Product struct {
+	gorm.Model
+	Name   string
+	Status string
+	Price  float64
+	Stock  int
+	Secret string `go-blar:"hidden"`
+}
+
+func setupTestDB(t *testing.T) (*gorm.DB, *meta.EntityMeta) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+
+	products := []Product{
+		{Name: "phone", Status: "active", Price: 199, Stock: 3},
+		{Name: "phone case", Status: "active", Price: 9.5, Stock: 0},
+		{Name: "laptop", Status: "draft", Price: 999, Stock: 1},
+		{Name: "cable", Status: "archived", Price: 5, Stock: 10},
+	}
+	if err := db.Create(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&products[3]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Product{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, entityMeta
+}
+
+func TestParseFiltersApply(t *testing.T) {
+	db, entityMeta := setupTestDB(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"name=laptop", []string{"laptop"}},
+		{"price[gte]=10", []string{"phone", "laptop"}},
+		{"price[lt]=200&stock[gt]=0", []string{"phone"}},
+		{"status[in]=active,draft&stock[ne]=3", []string{"phone case", "laptop"}},
+		{"name[like]=phone%25", []string{"phone", "phone case"}},
+		{"deleted_at[null]=true", []string{"phone", "phone case", "laptop"}},
+		{"page=1&limit=10", []string{"phone", "phone case", "laptop"}},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		filters, err := ParseFilters(values, entityMeta)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+
+		var products []Product
+		if err := ApplyFilters(db, filters).Order("id").Find(&products).Error; err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+
+		if len(products) != len(tt.expected) {
+			t.Fatalf("%s: expected %d products, got %d", tt.query, len(tt.expected), len(products))
+		}
+		for i, name := range tt.expected {
+			if products[i].Name != name {
+				t.Errorf("%s: expected %s at %d, got %s", tt.query, name, i, products[i].Name)
+			}
+		}
+	}
+}
+
+func TestParseFiltersInvalid(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	tests := []string{
+		"unknown=1",
+		"secret=x",
+		"price[between]=1",
+		"price[gte=1",
+		"price=cheap",
+		"stock[in]=1,two",
+		"price[like]=1%25",
+		"deleted_at[null]=maybe",
+	}
+
+	for _, q := range tests {
+		values, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseFilters(values, entityMeta); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
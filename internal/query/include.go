@@ -0,0 +1,140 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/hooks"
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+)
+
+// Include is a relation path to preload, parsed from ?include=items.product.
+type Include struct {
+	Path      string               // GORM preload path, e.g. "Items.Product"
+	Relation  *meta.RelationMeta   // top-level relation the path starts with
+	Relations []*meta.RelationMeta // relations along the path, starting with Relation
+}
+
+// ParseIncludes parses the relations to expand, such as ?include=user,tags,items.product,
+// and adds the entity's default relations. Relations are named by snake_case field
+// name, and paths may be at most maxDepth relations deep (unlimited if maxDepth <= 0).
+func ParseIncludes(values url.Values, entityMeta *meta.EntityMeta, maxDepth int) ([]Include, error) {
+	includes := make([]Include, 0)
+	seen := make(map[string]bool)
+	add := func(inc Include) {
+		if !seen[inc.Path] {
+			seen[inc.Path] = true
+			includes = append(includes, inc)
+		}
+	}
+
+	for _, rel := range entityMeta.Relations {
+		if rel.Default {
+			add(Include{Path: rel.Name, Relation: rel, Relations: []*meta.RelationMeta{rel}})
+		}
+	}
+
+	for _, raw := range values["include"] {
+		for _, param := range strings.Split(raw, ",") {
+			param = strings.TrimSpace(param)
+			if param == "" {
+				continue
+			}
+
+			parts := strings.Split(param, ".")
+			if maxDepth > 0 && len(parts) > maxDepth {
+				return nil, fmt.Errorf("include %q exceeds the maximum depth of %d", param, maxDepth)
+			}
+
+			inc, err := resolveInclude(entityMeta, parts)
+			if err != nil {
+				return nil, fmt.Errorf("unknown include %q", param)
+			}
+			add(inc)
+		}
+	}
+
+	return includes, nil
+}
+
+// ApplyIncludes preloads the included relations. Each relation along a path is
+// scoped by the BeforeList hook of its entity type, so includes only load rows
+// that type's list would return.
+func ApplyIncludes(db *gorm.DB, includes []Include) *gorm.DB {
+	for _, inc := range includes {
+		names := make([]string, 0, len(inc.Relations))
+		for _, rel := range inc.Relations {
+			names = append(names, rel.Name)
+			db = db.Preload(strings.Join(names, "."), scopeInclude(rel.Entity))
+		}
+	}
+	return db
+}
+
+// scopeInclude returns a preload condition calling the BeforeList hook of the
+// related entity type. Hook errors fail the query.
+func scopeInclude(entityMeta *meta.EntityMeta) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		scoped, err := hooks.CallBeforeList(db.Statement.Context, reflect.New(entityMeta.Type).Interface(), db)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return scoped
+	}
+}
+
+// IncludeKeys returns the foreign key columns that must be selected
+// so that the included fk relations can be loaded.
+func IncludeKeys(includes []Include) []*meta.FieldMeta {
+	keys := make([]*meta.FieldMeta, 0)
+	for _, inc := range includes {
+		if key := inc.Relation.Key; key != nil && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// resolveInclude follows a relation path through the entity metadata.
+func resolveInclude(entityMeta *meta.EntityMeta, parts []string) (Include, error) {
+	var inc Include
+	names := make([]string, 0, len(parts))
+
+	current := entityMeta
+	for _, part := range parts {
+		rel := current.GetRelationByParam(part)
+		if rel == nil || !rel.Field.Readable() {
+			return Include{}, fmt.Errorf("unknown relation %q on %s", part, current.Name)
+		}
+		if inc.Relation == nil {
+			inc.Relation = rel
+		}
+		inc.Relations = append(inc.Relations, rel)
+		names = append(names, rel.Name)
+		current = rel.Entity
+	}
+
+	inc.Path = strings.Join(names, ".")
+	return inc, nil
+}
+
+// IncludeFields adds the relation fields of includes to a sparse fieldset,
+// so that expanded relations are serialized. Nil fields stay nil.
+func IncludeFields(fields []*meta.FieldMeta, includes []Include) []*meta.FieldMeta {
+	if fields == nil {
+		return nil
+	}
+
+	fields = slices.Clone(fields)
+	for _, inc := range includes {
+		if !slices.Contains(fields, inc.Relation.Field) {
+			fields = append(fields, inc.Relation.Field)
+		}
+	}
+	return fields
+}
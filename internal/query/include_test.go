@@ -0,0 +1,193 @@
+package query
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// hiddenName is the context key of a name the Author and Label lists hide.
+type hiddenName struct{}
+
+// scopeName hides rows named by the context's hiddenName, if any.
+func scopeName(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	if name, ok := ctx.Value(hiddenName{}).(string); ok {
+		return db.Where("name <> ?", name), nil
+	}
+	return db, nil
+}
+
+type Author struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func (a *Author) BeforeList(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return scopeName(ctx, db)
+}
+
+type Label struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func (l *Label) BeforeList(ctx context.Context, db *gorm.DB) (*gorm.DB, error) {
+	return scopeName(ctx, db)
+}
+
+type Chapter struct {
+	ID       uint `gorm:"primaryKey"`
+	BookID   uint
+	Title    string
+	AuthorID uint    `go-blar:"fk:Author"`
+	Author   *Author `gorm:"foreignKey:AuthorID"`
+}
+
+type Book struct {
+	ID       uint `gorm:"primaryKey"`
+	Title    string
+	AuthorID uint      `go-blar:"fk:Author;include"`
+	Author   *Author   `gorm:"foreignKey:AuthorID"`
+	Labels   []Label   `go-blar:"m2m:book_labels" gorm:"many2many:book_labels"`
+	Chapters []Chapter `go-blar:"list"`
+}
+
+// setupIncludeDB creates a book with an author, labels and chapters.
+func setupIncludeDB(t *testing.T) (*gorm.DB, *meta.EntityMeta) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Author{}, &Label{}, &Chapter{}, &Book{}); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+
+	author := Author{Name: "ann"}
+	if err := db.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	book := Book{
+		Title:    "go",
+		AuthorID: author.ID,
+		Labels:   []Label{{Name: "tech"}, {Name: "new"}},
+		Chapters: []Chapter{{Title: "intro", AuthorID: author.ID}, {Title: "types", AuthorID: author.ID}},
+	}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Book{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, entityMeta
+}
+
+func TestParseIncludesApply(t *testing.T) {
+	db, entityMeta := setupIncludeDB(t)
+
+	includes, err := ParseIncludes(url.Values{"include": {"labels,chapters.author"}}, entityMeta, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	paths := make([]string, 0, len(includes))
+	for _, inc := range includes {
+		paths = append(paths, inc.Path)
+	}
+	if len(paths) != 3 || paths[0] != "Author" || paths[1] != "Labels" || paths[2] != "Chapters.Author" {
+		t.Fatalf("expected default Author then requested paths, got %v", paths)
+	}
+
+	var book Book
+	if err := ApplyIncludes(db, includes).First(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if book.Author == nil || book.Author.Name != "ann" {
+		t.Fatalf("expected author to be loaded, got %+v", book.Author)
+	}
+	if len(book.Labels) != 2 {
+		t.Fatalf("expected 2 labels, got %d", len(book.Labels))
+	}
+	if len(book.Chapters) != 2 || book.Chapters[0].Author == nil {
+		t.Fatalf("expected chapters with authors, got %+v", book.Chapters)
+	}
+}
+
+func TestApplyIncludesScoped(t *testing.T) {
+	db, entityMeta := setupIncludeDB(t)
+
+	includes, err := ParseIncludes(url.Values{"include": {"labels,chapters.author"}}, entityMeta, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Related rows hidden by their BeforeList hook are not loaded, also nested
+	var book Book
+	ctx := context.WithValue(context.Background(), hiddenName{}, "ann")
+	if err := ApplyIncludes(db.WithContext(ctx), includes).First(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	if book.Author != nil {
+		t.Fatalf("expected hidden author not to be loaded, got %+v", book.Author)
+	}
+	if len(book.Chapters) != 2 || book.Chapters[0].Author != nil || book.Chapters[1].Author != nil {
+		t.Fatalf("expected chapters without their hidden author, got %+v", book.Chapters)
+	}
+
+	book = Book{}
+	ctx = context.WithValue(context.Background(), hiddenName{}, "tech")
+	if err := ApplyIncludes(db.WithContext(ctx), includes).First(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Labels) != 1 || book.Labels[0].Name != "new" {
+		t.Fatalf("expected only the visible label, got %+v", book.Labels)
+	}
+}
+
+func TestParseIncludesInvalid(t *testing.T) {
+	_, entityMeta := setupIncludeDB(t)
+
+	for _, q := range []string{"include=unknown", "include=title", "include=chapters.book", "include=chapters.author"} {
+		values, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseIncludes(values, entityMeta, 1); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
+
+func TestIncludeKeysAndFields(t *testing.T) {
+	_, entityMeta := setupIncludeDB(t)
+
+	includes, err := ParseIncludes(url.Values{"include": {"chapters"}}, entityMeta, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := IncludeKeys(includes)
+	if len(keys) != 1 || keys[0].Column != "author_id" {
+		t.Fatalf("expected author_id foreign key to be selected, got %v", keys)
+	}
+
+	fields := IncludeFields([]*meta.FieldMeta{entityMeta.PKField}, includes)
+	if len(fields) != 3 || fields[1].Name != "Author" || fields[2].Name != "Chapters" {
+		t.Fatalf("expected relation fields to be added to the fieldset, got %v", fields)
+	}
+
+	if IncludeFields(nil, includes) != nil {
+		t.Fatal("expected nil fieldset to stay nil")
+	}
+}
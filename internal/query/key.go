@@ -0,0 +1,67 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoPrimaryKey is returned for entities without a primary key field.
+var ErrNoPrimaryKey = errors.New("entity has no primary key")
+
+// ParseKey parses the escaped {id} URL path segment into primary key values
+// converted to the types of the key fields. Composite keys are separated by
+// commas in key field order, e.g. "3,7" for (product_id, tag_id); parts are
+// path-unescaped, so commas inside them can be sent as %2C.
+func ParseKey(entityMeta *meta.EntityMeta, raw string) ([]any, error) {
+	fields := entityMeta.PKFields
+	if len(fields) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+
+	parts := []string{raw}
+	if len(fields) > 1 {
+		parts = strings.Split(raw, ",")
+		if len(parts) != len(fields) {
+			return nil, fmt.Errorf("expected %d comma-separated key values", len(fields))
+		}
+	}
+
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		part, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = ConvertValue(f.Type, part); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.Column, err)
+		}
+	}
+
+	return values, nil
+}
+
+// ApplyKey restricts the query to the row with the given primary key values.
+func ApplyKey(db *gorm.DB, entityMeta *meta.EntityMeta, values []any) *gorm.DB {
+	for i, f := range entityMeta.PKFields {
+		db = db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.Column}, Value: values[i]})
+	}
+	return db
+}
+
+// KeyOf returns the primary key values of entity, a pointer to a struct.
+func KeyOf(entityMeta *meta.EntityMeta, entity any) []any {
+	v := reflect.ValueOf(entity).Elem()
+
+	values := make([]any, 0, len(entityMeta.PKFields))
+	for _, f := range entityMeta.PKFields {
+		values = append(values, f.Value(v).Interface())
+	}
+	return values
+}
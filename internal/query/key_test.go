@@ -0,0 +1,67 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+)
+
+type Listing struct {
+	ProductID uint   `gorm:"primaryKey"`
+	Region    string `gorm:"primaryKey"`
+	Price     float64
+}
+
+func TestParseKey(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	values, err := ParseKey(entityMeta, "42")
+	if err != nil || !reflect.DeepEqual(values, []any{uint64(42)}) {
+		t.Fatalf("expected [42], got %v, %v", values, err)
+	}
+
+	if _, err := ParseKey(entityMeta, "abc"); err == nil {
+		t.Fatal("expected error for non-numeric key")
+	}
+
+	meta.ClearRegistry()
+	listingMeta, err := meta.Parse(&Listing{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err = ParseKey(listingMeta, "7,eu%2Cwest")
+	if err != nil || !reflect.DeepEqual(values, []any{uint64(7), "eu,west"}) {
+		t.Fatalf("expected [7 eu,west], got %v, %v", values, err)
+	}
+
+	for _, raw := range []string{"7", "7,eu,west", "x,eu"} {
+		if _, err := ParseKey(listingMeta, raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestApplyKey(t *testing.T) {
+	db, _ := setupTestDB(t)
+
+	meta.ClearRegistry()
+	listingMeta, err := meta.Parse(&Listing{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listing := &Listing{ProductID: 7, Region: "eu"}
+	key := KeyOf(listingMeta, listing)
+	if !reflect.DeepEqual(key, []any{uint(7), "eu"}) {
+		t.Fatalf("expected [7 eu], got %v", key)
+	}
+
+	stmt := ApplyKey(db.Session(&gorm.Session{DryRun: true}), listingMeta, key).First(&Listing{}).Statement
+	if sql := stmt.SQL.String(); !strings.HasPrefix(sql, "SELECT * FROM `listings` WHERE `listings`.`product_id` = ? AND `listings`.`region` = ?") {
+		t.Fatalf("unexpected SQL: %s", sql)
+	}
+}
@@ -0,0 +1,126 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPageSize is the page size used when the configured default is below 1.
+const DefaultPageSize = 20
+
+// Page is the window of results requested with ?page=&per_page= or ?limit=&offset=.
+type Page struct {
+	Number int // 1-based page number
+	Size   int // number of results per page
+	Offset int // number of results skipped
+
+	// limitOffset is set when the request used ?limit=&offset=,
+	// so that links are generated in the same style.
+	limitOffset bool
+}
+
+// ParsePage parses pagination parameters. Missing sizes use defaultSize, or
+// DefaultPageSize if it is below 1, and sizes above maxSize are capped unless
+// maxSize is 0. Page numbers start at 1.
+func ParsePage(values url.Values, defaultSize, maxSize int) (Page, error) {
+	if defaultSize < 1 {
+		defaultSize = DefaultPageSize
+	}
+	page := Page{Number: 1, Size: defaultSize}
+
+	if _, ok := values["limit"]; ok {
+		page.limitOffset = true
+	} else if _, ok := values["offset"]; ok {
+		page.limitOffset = true
+	}
+
+	var err error
+	if page.limitOffset {
+		if page.Size, err = intParam(values, "limit", defaultSize, 1); err != nil {
+			return Page{}, err
+		}
+		if page.Offset, err = intParam(values, "offset", 0, 0); err != nil {
+			return Page{}, err
+		}
+	} else {
+		if page.Size, err = intParam(values, "per_page", defaultSize, 1); err != nil {
+			return Page{}, err
+		}
+		if page.Number, err = intParam(values, "page", 1, 1); err != nil {
+			return Page{}, err
+		}
+	}
+
+	if maxSize > 0 && page.Size > maxSize {
+		page.Size = maxSize
+	}
+
+	if page.limitOffset {
+		page.Number = page.Offset/page.Size + 1
+	} else {
+		page.Offset = (page.Number - 1) * page.Size
+	}
+
+	return page, nil
+}
+
+// Apply limits the query to the page.
+func (p Page) Apply(db *gorm.DB) *gorm.DB {
+	return db.Limit(p.Size).Offset(p.Offset)
+}
+
+// Links returns first, prev, next and last links for the page, keeping the
+// other query parameters of u. Links that do not exist for the page are omitted.
+func (p Page) Links(u *url.URL, total int64) map[string]string {
+	links := make(map[string]string)
+
+	last := 0
+	if total > 0 {
+		last = int((total - 1) / int64(p.Size))
+	}
+	lastOffset := last * p.Size
+
+	links["first"] = p.link(u, 0)
+	if p.Offset > 0 {
+		links["prev"] = p.link(u, max(p.Offset-p.Size, 0))
+	}
+	if int64(p.Offset+p.Size) < total {
+		links["next"] = p.link(u, p.Offset+p.Size)
+	}
+	links["last"] = p.link(u, lastOffset)
+
+	return links
+}
+
+// link returns u with its pagination parameters set to the page at offset.
+func (p Page) link(u *url.URL, offset int) string {
+	values := u.Query()
+	if p.limitOffset {
+		values.Set("limit", strconv.Itoa(p.Size))
+		values.Set("offset", strconv.Itoa(offset))
+	} else {
+		values.Set("per_page", strconv.Itoa(p.Size))
+		values.Set("page", strconv.Itoa(offset/p.Size+1))
+	}
+
+	link := *u
+	link.RawQuery = values.Encode()
+	return link.String()
+}
+
+// intParam parses an integer parameter that must be at least minimum.
+func intParam(values url.Values, name string, fallback, minimum int) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minimum {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer of at least %d", name, raw, minimum)
+	}
+	return n, nil
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query  string
+		number int
+		size   int
+		offset int
+	}{
+		{"", 1, 20, 0},
+		{"page=3&per_page=10", 3, 10, 20},
+		{"per_page=500", 1, 100, 0},
+		{"limit=5&offset=15", 4, 5, 15},
+		{"offset=40", 3, 20, 40},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := ParsePage(values, 20, 100)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+
+		if page.Number != tt.number || page.Size != tt.size || page.Offset != tt.offset {
+			t.Errorf("%s: expected page=%d size=%d offset=%d, got page=%d size=%d offset=%d",
+				tt.query, tt.number, tt.size, tt.offset, page.Number, page.Size, page.Offset)
+		}
+	}
+}
+
+func TestParsePageZeroDefault(t *testing.T) {
+	for _, defaultSize := range []int{0, -10} {
+		page, err := ParsePage(url.Values{"offset": {"45"}}, defaultSize, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Size != DefaultPageSize || page.Number != 3 {
+			t.Fatalf("default %d: expected fallback page size, got %+v", defaultSize, page)
+		}
+
+		u := &url.URL{Path: "/product"}
+		if last := page.Links(u, 100)["last"]; last != "/product?limit=20&offset=80" {
+			t.Errorf("default %d: unexpected last link %s", defaultSize, last)
+		}
+
+		cursorPage, err := ParseCursorPage(url.Values{}, defaultSize, 0)
+		if err != nil || cursorPage.Size != DefaultPageSize {
+			t.Errorf("default %d: expected fallback cursor page size, got %+v, %v", defaultSize, cursorPage, err)
+		}
+	}
+}
+
+func TestParsePageInvalid(t *testing.T) {
+	for _, q := range []string{"page=0", "page=abc", "per_page=0", "limit=-1", "offset=-5"} {
+		values, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParsePage(values, 20, 100); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	u, err := url.Parse("/product?name=phone&page=2&per_page=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ParsePage(u.Query(), 20, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := page.Links(u, 35)
+
+	expected := map[string]string{
+		"first": "/product?name=phone&page=1&per_page=10",
+		"prev":  "/product?name=phone&page=1&per_page=10",
+		"next":  "/product?name=phone&page=3&per_page=10",
+		"last":  "/product?name=phone&page=4&per_page=10",
+	}
+	for rel, link := range expected {
+		if links[rel] != link {
+			t.Errorf("expected %s link %s, got %s", rel, link, links[rel])
+		}
+	}
+
+	lastPage, _ := ParsePage(url.Values{"page": {"4"}, "per_page": {"10"}}, 20, 100)
+	if _, ok := lastPage.Links(u, 35)["next"]; ok {
+		t.Error("expected no next link on the last page")
+	}
+}
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sort is a single ordering parsed from ?sort=, e.g. "-created_at".
+type Sort struct {
+	Field *meta.FieldMeta
+	Desc  bool
+}
+
+// ParseSort parses ?sort=-created_at,name into orderings.
+// A leading "-" sorts descending. Fields are named by column and must be visible columns.
+// The primary key is appended as a tie-breaker so that pages are stable.
+func ParseSort(values url.Values, entityMeta *meta.EntityMeta) ([]Sort, error) {
+	sorts := make([]Sort, 0)
+
+	for _, raw := range values["sort"] {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			name, desc := strings.CutPrefix(part, "-")
+			field := entityMeta.GetFieldByColumn(name)
+			if field == nil || !field.Readable() || !field.IsColumn() {
+				return nil, fmt.Errorf("unknown sort field %q", name)
+			}
+
+			sorts = append(sorts, Sort{Field: field, Desc: desc})
+		}
+	}
+
+	for _, pk := range entityMeta.PKFields {
+		if !hasSort(sorts, pk) {
+			sorts = append(sorts, Sort{Field: pk})
+		}
+	}
+
+	return sorts, nil
+}
+
+// ApplySort adds the orderings to the query.
+func ApplySort(db *gorm.DB, sorts []Sort) *gorm.DB {
+	if len(sorts) == 0 {
+		return db
+	}
+
+	columns := make([]clause.OrderByColumn, 0, len(sorts))
+	for _, s := range sorts {
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: s.Field.Column},
+			Desc:   s.Desc,
+		})
+	}
+
+	return db.Order(clause.OrderBy{Columns: columns})
+}
+
+// hasSort reports whether the field is already sorted on.
+func hasSort(sorts []Sort, field *meta.FieldMeta) bool {
+	for _, s := range sorts {
+		if s.Field == field {
+			return true
+		}
+	}
+	return false
+}
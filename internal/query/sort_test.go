@@ -0,0 +1,71 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseSortApply(t *testing.T) {
+	db, entityMeta := setupTestDB(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"phone", "phone case", "laptop"}},
+		{"sort=name", []string{"laptop", "phone", "phone case"}},
+		{"sort=-price", []string{"laptop", "phone", "phone case"}},
+		{"sort=status,-stock", []string{"phone", "phone case", "laptop"}},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sorts, err := ParseSort(values, entityMeta)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+
+		var products []Product
+		if err := ApplySort(db, sorts).Find(&products).Error; err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+
+		for i, name := range tt.expected {
+			if products[i].Name != name {
+				t.Errorf("%s: expected %s at %d, got %s", tt.query, name, i, products[i].Name)
+			}
+		}
+	}
+}
+
+func TestParseSortPrimaryKeyTieBreaker(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	sorts, err := ParseSort(url.Values{"sort": {"-name"}}, entityMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sorts) != 2 || sorts[1].Field != entityMeta.PKField || sorts[1].Desc {
+		t.Fatalf("expected primary key tie-breaker, got %+v", sorts)
+	}
+}
+
+func TestParseSortInvalid(t *testing.T) {
+	_, entityMeta := setupTestDB(t)
+
+	for _, q := range []string{"sort=unknown", "sort=-secret", "sort=name%3Bdrop"} {
+		values, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseSort(values, entityMeta); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
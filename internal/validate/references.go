@@ -0,0 +1,44 @@
+package validate
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// references checks that the written fk: fields of entity, a pointer to a
+// struct, refer to existing rows, with one query per foreign key. Zero values
+// refer to nothing and are not checked, and neither are the fields in skip.
+// It returns an error for each field with a dangling reference.
+func references(db *gorm.DB, entityMeta *meta.EntityMeta, entity any, written, skip []*meta.FieldMeta) (Errors, error) {
+	v := reflect.ValueOf(entity).Elem()
+
+	var errs Errors
+	for _, f := range written {
+		target := entityMeta.ReferencedEntity(f)
+		if target == nil || len(target.PKFields) != 1 || slices.Contains(skip, f) {
+			continue
+		}
+
+		value := f.Value(v)
+		if value.IsZero() {
+			continue
+		}
+
+		var found int64
+		pk := target.PKFields[0]
+		err := db.Model(reflect.New(target.Type).Interface()).
+			Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.Column}, Value: reflect.Indirect(value).Interface()}).
+			Count(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		if found == 0 {
+			errs = append(errs, FieldError{Field: f.JSONName, Message: "must reference an existing " + target.Name})
+		}
+	}
+	return errs, nil
+}
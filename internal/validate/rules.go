@@ -0,0 +1,145 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+func init() {
+	Register("min", minRule)
+	Register("max", maxRule)
+	Register("len", lenRule)
+	Register("email", emailRule)
+	Register("url", urlRule)
+	Register("oneof", oneOfRule)
+	Register("regex", regexRule)
+}
+
+// minRule checks that a number is at least param, or that a string, slice or
+// map has at least param characters or items.
+func minRule(ctx context.Context, value any, param string) error {
+	return compare(value, param, "at least", func(a, b float64) bool { return a >= b })
+}
+
+// maxRule checks that a number is at most param, or that a string, slice or
+// map has at most param characters or items.
+func maxRule(ctx context.Context, value any, param string) error {
+	return compare(value, param, "at most", func(a, b float64) bool { return a <= b })
+}
+
+// lenRule checks that a string, slice or map has exactly param characters or items.
+func lenRule(ctx context.Context, value any, param string) error {
+	n, ok := length(reflect.ValueOf(value))
+	if !ok {
+		return fmt.Errorf("has no length")
+	}
+	want, err := strconv.Atoi(param)
+	if err != nil {
+		return fmt.Errorf("has an invalid len rule %q", param)
+	}
+	if n != want {
+		return fmt.Errorf("must have a length of %d", want)
+	}
+	return nil
+}
+
+// compare checks the value or length of value against the number in param.
+func compare(value any, param, relation string, ok func(a, b float64) bool) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("has an invalid limit %q", param)
+	}
+
+	v := reflect.ValueOf(value)
+	if n, isLen := length(v); isLen {
+		if !ok(float64(n), limit) {
+			return fmt.Errorf("must have a length of %s %s", relation, param)
+		}
+		return nil
+	}
+
+	var f float64
+	switch {
+	case v.CanInt():
+		f = float64(v.Int())
+	case v.CanUint():
+		f = float64(v.Uint())
+	case v.CanFloat():
+		f = v.Float()
+	default:
+		return errors.New("is not a number")
+	}
+	if !ok(f, limit) {
+		return fmt.Errorf("must be %s %s", relation, param)
+	}
+	return nil
+}
+
+// length returns the length of a string in characters, or of a slice or map.
+func length(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+// emailRule checks that a string is a plain email address such as a@example.com.
+func emailRule(ctx context.Context, value any, param string) error {
+	s, _ := value.(string)
+	if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+// urlRule checks that a string is an absolute URL.
+func urlRule(ctx context.Context, value any, param string) error {
+	s, _ := value.(string)
+	if u, err := url.ParseRequestURI(s); err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("must be a valid URL")
+	}
+	return nil
+}
+
+// oneOfRule checks that a value is one of the space-separated values in param.
+func oneOfRule(ctx context.Context, value any, param string) error {
+	options := strings.Fields(param)
+	if !slices.Contains(options, fmt.Sprint(value)) {
+		return fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+	}
+	return nil
+}
+
+// patterns caches compiled regex rule patterns.
+var patterns sync.Map
+
+// regexRule checks that a string matches the regular expression in param.
+func regexRule(ctx context.Context, value any, param string) error {
+	re, ok := patterns.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return fmt.Errorf("has an invalid pattern %q", param)
+		}
+		re, _ = patterns.LoadOrStore(param, compiled)
+	}
+
+	s, _ := value.(string)
+	if !re.(*regexp.Regexp).MatchString(s) {
+		return errors.New("has an invalid format")
+	}
+	return nil
+}
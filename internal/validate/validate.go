@@ -0,0 +1,186 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"github.com/kamil5b/go-blar/internal/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FieldError describes an invalid field in a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists the invalid fields of an entity. Handlers report it as
+// 422 Unprocessable Entity.
+type Errors []FieldError
+
+// Error joins the field errors into one message.
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fmt.Sprintf("%s %s", fe.Field, fe.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Func checks a field value against a rule with the given parameter, e.g. "50"
+// for max=50. Pointers are dereferenced, and nil pointers are only checked by
+// required. The returned error's message describes the problem, e.g.
+// "must be at most 50".
+type Func func(ctx context.Context, value any, param string) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = make(map[string]Func)
+)
+
+// Register registers a validation rule under name, replacing any previous one,
+// including the built-in rules.
+func Register(name string, fn Func) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+// Validator is implemented by entities with rules that span several fields or
+// need database lookups. Validate is called after the field rules, even when
+// they fail, and returns the invalid fields, or nil.
+type Validator interface {
+	Validate(ctx context.Context, tx *gorm.DB) Errors
+}
+
+// Op is the write an entity is validated for.
+type Op int
+
+const (
+	// Create validates a new entity.
+	Create Op = iota
+	// Update validates a stored entity with the request's changes applied.
+	Update
+)
+
+// Entity checks the rules of the writable fields of entity, a pointer to a
+// struct, and that its written fk: fields refer to existing rows, then calls
+// its Validate method if it implements Validator.
+// On Update, write-only fields are only checked when written, since the stored
+// value may have been transformed; on Create they are always checked. Every
+// field is checked, and each reports its first failing rule. It returns Errors
+// listing all invalid fields, or another error if a rule is unknown or the
+// database cannot be queried.
+func Entity(ctx context.Context, db *gorm.DB, op Op, entityMeta *meta.EntityMeta, entity any, written []*meta.FieldMeta) error {
+	v := reflect.ValueOf(entity).Elem()
+
+	var errs Errors
+	var invalid []*meta.FieldMeta
+	for _, f := range entityMeta.Fields {
+		if len(f.Validate) == 0 || !f.Writable() || (op == Update && f.WriteOnly && !slices.Contains(written, f)) {
+			continue
+		}
+
+		msg, err := checkField(ctx, db, entityMeta, entity, f, f.Value(v))
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			errs = append(errs, FieldError{Field: f.JSONName, Message: msg})
+			invalid = append(invalid, f)
+		}
+	}
+
+	// Written foreign keys must refer to existing rows
+	refErrs, err := references(db, entityMeta, entity, written, invalid)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, refErrs...)
+
+	if v, ok := entity.(Validator); ok {
+		errs = append(errs, v.Validate(ctx, db)...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkField checks the rules of field f in order and returns the message of
+// the first one that fails, or "" if the value is valid.
+func checkField(ctx context.Context, db *gorm.DB, entityMeta *meta.EntityMeta, entity any, f *meta.FieldMeta, value reflect.Value) (string, error) {
+	for _, rule := range f.Validate {
+		if rule.Name == "required" {
+			if value.IsZero() {
+				return "is required", nil
+			}
+			continue
+		}
+
+		// Unset optional values are not checked
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			continue
+		}
+
+		if rule.Name == "unique" {
+			taken, err := taken(db, entityMeta, entity, f, value.Interface())
+			if err != nil {
+				return "", err
+			}
+			if taken {
+				return "must be unique", nil
+			}
+			continue
+		}
+
+		validatorsMu.RLock()
+		fn, ok := validators[rule.Name]
+		validatorsMu.RUnlock()
+		if !ok {
+			return "", fmt.Errorf("field %s: unknown validation rule %q", f.Name, rule.Name)
+		}
+
+		if err := fn(ctx, reflect.Indirect(value).Interface(), rule.Param); err != nil {
+			return err.Error(), nil
+		}
+	}
+	return "", nil
+}
+
+// taken reports whether another row than entity has value in f's column.
+func taken(db *gorm.DB, entityMeta *meta.EntityMeta, entity any, f *meta.FieldMeta, value any) (bool, error) {
+	rows := reflect.New(reflect.SliceOf(entityMeta.Type))
+	err := db.Model(reflect.New(entityMeta.Type).Interface()).
+		Select(keyColumns(entityMeta)).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.Column}, Value: value}).
+		Limit(2).
+		Find(rows.Interface()).Error
+	if err != nil {
+		return false, err
+	}
+
+	key := query.KeyOf(entityMeta, entity)
+	for i := 0; i < rows.Elem().Len(); i++ {
+		if !reflect.DeepEqual(query.KeyOf(entityMeta, rows.Elem().Index(i).Addr().Interface()), key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keyColumns returns the primary key columns of the entity.
+func keyColumns(entityMeta *meta.EntityMeta) []string {
+	columns := make([]string, 0, len(entityMeta.PKFields))
+	for _, pk := range entityMeta.PKFields {
+		columns = append(columns, pk.Column)
+	}
+	return columns
+}
@@ -0,0 +1,252 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kamil5b/go-blar/internal/meta"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRules(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		rule  string
+		value any
+		param string
+		valid bool
+	}{
+		{"min", 3, "3", true},
+		{"min", 2.5, "3", false},
+		{"min", "héé", "3", true},
+		{"min", "ab", "3", false},
+		{"max", uint(50), "50", true},
+		{"max", []int{1, 2}, "1", false},
+		{"max", true, "1", false},
+		{"len", "abcd", "4", true},
+		{"len", "abc", "4", false},
+		{"email", "a@example.com", "", true},
+		{"email", "Ann <a@example.com>", "", false},
+		{"email", "example.com", "", false},
+		{"url", "https://example.com/x", "", true},
+		{"url", "/x", "", false},
+		{"oneof", "green", "red green", true},
+		{"oneof", 3, "1 2", false},
+		{"regex", "AB1", "^[A-Z]+[0-9]$", true},
+		{"regex", "ab1", "^[A-Z]+[0-9]$", false},
+		{"regex", "x", "[", false},
+	}
+
+	for _, tt := range tests {
+		err := validators[tt.rule](ctx, tt.value, tt.param)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s=%s on %v: expected valid=%v, got %v", tt.rule, tt.param, tt.value, tt.valid, err)
+		}
+	}
+}
+
+type Signup struct {
+	ID       uint    `gorm:"primaryKey"`
+	Email    string  `go-blar:"validate:required,email,unique"`
+	Name     string  `json:"name" go-blar:"validate:required,max=5"`
+	Nick     *string `go-blar:"validate:min=2"`
+	Password string  `go-blar:"writeonly;validate:min=8"`
+	Role     string  `go-blar:"readonly;validate:required"`
+}
+
+func TestEntity(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Signup{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Signup{Email: "taken@x.io", Name: "ann"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := entityMeta.GetFieldByName("Password")
+
+	// All fields are reported; readonly and, on update, unwritten write-only fields are skipped
+	nick := "x"
+	err = Entity(ctx, db, Update, entityMeta, &Signup{Email: "taken@x.io", Name: "toolong", Nick: &nick, Password: "short"}, nil)
+	want := Errors{
+		{Field: "Email", Message: "must be unique"},
+		{Field: "name", Message: "must have a length of at most 5"},
+		{Field: "Nick", Message: "must have a length of at least 2"},
+	}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	err = Entity(ctx, db, Update, entityMeta, &Signup{Name: "bob", Password: "short"}, []*meta.FieldMeta{password})
+	want = Errors{
+		{Field: "Email", Message: "is required"},
+		{Field: "Password", Message: "must have a length of at least 8"},
+	}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	// A new entity has its write-only fields checked even when the body left them out
+	err = Entity(ctx, db, Create, entityMeta, &Signup{Email: "bob@x.io", Name: "bob"}, nil)
+	want = Errors{{Field: "Password", Message: "must have a length of at least 8"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	// The entity's own row does not make its value taken
+	if err := Entity(ctx, db, Update, entityMeta, &Signup{ID: 1, Email: "taken@x.io", Name: "ann"}, nil); err != nil {
+		t.Fatalf("expected valid entity, got %v", err)
+	}
+}
+
+func TestEntityCustomRule(t *testing.T) {
+	type Coupon struct {
+		ID   uint
+		Code string `go-blar:"validate:upper"`
+		Note string `go-blar:"validate:unknown"`
+	}
+
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Coupon{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Register("upper", func(ctx context.Context, value any, param string) error {
+		if s := value.(string); s != "" && s[0] >= 'a' {
+			return errors.New("must be upper case")
+		}
+		return nil
+	})
+
+	err = Entity(context.Background(), nil, Create, entityMeta, &Coupon{Code: "abc"}, nil)
+	var errs Errors
+	if errors.As(err, &errs) || err == nil {
+		t.Fatalf("expected unknown rule error, got %v", err)
+	}
+
+	entityMeta.GetFieldByName("Note").Validate = nil
+	err = Entity(context.Background(), nil, Create, entityMeta, &Coupon{Code: "abc"}, nil)
+	if want := (Errors{{Field: "Code", Message: "must be upper case"}}); !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+// Booking checks its dates and room with Validate.
+type Booking struct {
+	ID    uint
+	Room  string `json:"room" go-blar:"validate:required"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+func (b *Booking) Validate(ctx context.Context, tx *gorm.DB) Errors {
+	var errs Errors
+	if b.End <= b.Start {
+		errs = append(errs, FieldError{Field: "end", Message: "must be after start"})
+	}
+	if b.Room == "attic" {
+		errs = append(errs, FieldError{Field: "room", Message: "is closed"})
+	}
+	return errs
+}
+
+func TestEntityValidator(t *testing.T) {
+	meta.ClearRegistry()
+	entityMeta, err := meta.Parse(&Booking{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Validate runs after the field rules and its errors are added to theirs
+	err = Entity(ctx, nil, Create, entityMeta, &Booking{Start: 2, End: 1}, nil)
+	want := Errors{{Field: "room", Message: "is required"}, {Field: "end", Message: "must be after start"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	err = Entity(ctx, nil, Create, entityMeta, &Booking{Room: "attic", Start: 1, End: 2}, nil)
+	if want := (Errors{{Field: "room", Message: "is closed"}}); !reflect.DeepEqual(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	if err := Entity(ctx, nil, Create, entityMeta, &Booking{Room: "hall", Start: 1, End: 2}, nil); err != nil {
+		t.Fatalf("expected valid booking, got %v", err)
+	}
+}
+
+type Owner struct {
+	ID   uint
+	Name string
+}
+
+type Pet struct {
+	ID       uint
+	Name     string `json:"name"`
+	OwnerID  uint   `json:"owner_id" go-blar:"fk:Owner"`
+	KeeperID *uint  `json:"keeper_id" go-blar:"fk:owners;validate:max=10"`
+}
+
+func TestEntityReferences(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Owner{}, &Pet{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&Owner{Name: "ann"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	meta.ClearRegistry()
+	ownerMeta, err := meta.Parse(&Owner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entityMeta, err := meta.Parse(&Pet{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.ResolveForeignKeys([]*meta.EntityMeta{ownerMeta, entityMeta})
+	ownerID, keeperID := entityMeta.GetFieldByName("OwnerID"), entityMeta.GetFieldByName("KeeperID")
+	written := []*meta.FieldMeta{ownerID, keeperID}
+
+	one, missing, tooBig := uint(1), uint(9), uint(11)
+	tests := []struct {
+		pet     *Pet
+		written []*meta.FieldMeta
+		want    error
+	}{
+		{&Pet{OwnerID: 1, KeeperID: &one}, written, nil},
+		{&Pet{OwnerID: 0, KeeperID: nil}, written, nil},
+		{&Pet{OwnerID: 2, KeeperID: &missing}, written, Errors{
+			{Field: "owner_id", Message: "must reference an existing Owner"},
+			{Field: "keeper_id", Message: "must reference an existing Owner"},
+		}},
+		// Unwritten foreign keys and fields failing their rules are not looked up
+		{&Pet{OwnerID: 2, KeeperID: &tooBig}, []*meta.FieldMeta{keeperID}, Errors{
+			{Field: "keeper_id", Message: "must be at most 10"},
+		}},
+	}
+
+	for i, tt := range tests {
+		if err := Entity(ctx, db, Create, entityMeta, tt.pet, tt.written); !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("%d: expected %v, got %v", i, tt.want, err)
+		}
+	}
+}
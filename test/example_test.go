@@ -19,7 +19,7 @@ type User struct {
 	ID       uint `gorm:"primaryKey" go-blar:"pk"`
 	Name     string
 	Email    string
-	Password string
+	Password string `go-blar:"writeonly"`
 }
 
 // Product is a sample entity.